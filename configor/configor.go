@@ -1,16 +1,52 @@
 package configor
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"reflect"
 	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 type Configor struct {
 	*Config
 	configModTimes map[string]time.Time
+
+	// current holds the latest loaded configuration, see Current
+	current atomic.Value
+
+	subsMu sync.Mutex
+	subs   []chan Change
+
+	// onReload is called with every reloaded configuration as it is swapped
+	// in, see Watcher
+	onReload func(next interface{})
+
+	// stopWatch stops auto reload when closed, and watching tracks the
+	// reload goroutines, see Watcher.Close
+	stopWatch chan struct{}
+	watching  sync.WaitGroup
+
+	// dotenvKeys are the env variables set from dotenv files
+	dotenvKeys map[string]bool
+
+	// flagValues are the flags set on the command-line, by the env name of
+	// their fields
+	flagValues map[string]*flagValue
+
+	// provenance holds the ProvenanceReport of the latest load, tracking
+	// the one in progress
+	provenance atomic.Value
+	tracking   *provenanceTracker
+
+	// sourceData is the configuration fetched from Sources
+	sourceData []*SourceData
 }
 
 type Config struct {
@@ -22,6 +58,55 @@ type Config struct {
 	AutoReload         bool
 	AutoReloadInterval time.Duration
 	AutoReloadCallback func(config interface{})
+	// AutoReloadErrorCallback is called with the errors of auto reload,
+	// which otherwise are only logged.
+	AutoReloadErrorCallback func(err error)
+
+	// AutoReloadWatch makes auto reload watch the configuration files for
+	// changes instead of polling them every AutoReloadInterval. Editors
+	// saving by atomic rename and Kubernetes ConfigMap symlink swaps are
+	// picked up as well. Auto reload falls back to polling where watching
+	// files is not supported.
+	AutoReloadWatch bool
+	// AutoReloadDebounce is how long watched files must stay unchanged
+	// before they are reloaded, 100ms by default.
+	AutoReloadDebounce time.Duration
+
+	// SafeReload stops auto reload from writing reloaded configurations into
+	// the struct passed to Load, which races with its readers. Read them
+	// with Current or Subscribe instead; AutoReloadCallback then receives
+	// the new configuration.
+	SafeReload bool
+
+	// Sources are loaded after configuration files, and polled for changes
+	// by auto reload.
+	Sources []Source
+
+	// Resolvers resolve references like `${vault:db/password}` in string
+	// values, by the name before the colon. References to `env` variables
+	// and `file` contents are resolved out of the box.
+	Resolvers map[string]Resolver
+
+	// FlagSet enables loading configurations from command-line flags, which
+	// take precedence over shell env. Load defines a flag on it for every
+	// field, named like `--db.host`, and parses Args on the first Load. A
+	// flag.ErrHelp error is returned when help was requested with `--help`.
+	FlagSet *flag.FlagSet
+	// Args are the command-line arguments parsed by FlagSet, os.Args[1:]
+	// by default.
+	Args []string
+
+	// TrackProvenance records where every field was loaded from, see
+	// Provenance.
+	TrackProvenance bool
+
+	// Logger receives diagnostics, which are printed to stdout by default.
+	Logger Logger
+
+	// ErrorOnMissingFiles makes Load return a *FileNotFoundError when one
+	// of the files, its environment file and its example file are all
+	// missing, instead of only warning about it.
+	ErrorOnMissingFiles bool
 
 	// In case of json files, this field will be used only when compiled with
 	// go 1.10 or later.
@@ -29,6 +114,76 @@ type Config struct {
 	ErrorOnUnmatchedKeys bool
 }
 
+// UnmatchedTomlKeysError errors are returned by the Load function when
+// ErrorOnUnmatchedKeys is set to true and there are unmatched keys in the input
+// toml config file. The string returned by Error() contains the names of the
+// missing keys.
+type UnmatchedTomlKeysError struct {
+	Keys []toml.Key
+}
+
+func (e *UnmatchedTomlKeysError) Error() string {
+	return fmt.Sprintf("There are keys in the config file that do not match any field in the given struct: %v", e.Keys)
+}
+
+// UnmatchedDotenvKeysError errors are returned by the Load function when
+// ErrorOnUnmatchedKeys is set to true and there are variables in the input
+// dotenv files that are not loaded into any field of the given struct.
+type UnmatchedDotenvKeysError struct {
+	Keys []string
+}
+
+func (e *UnmatchedDotenvKeysError) Error() string {
+	return fmt.Sprintf("There are variables in the dotenv file that do not match any field in the given struct: %v", e.Keys)
+}
+
+// FileNotFoundError errors are returned by the Load function when
+// ErrorOnMissingFiles is set to true and a configuration file is not found.
+type FileNotFoundError struct {
+	File string
+}
+
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("failed to find configuration %v", e.File)
+}
+
+// DecodeError errors are returned by the Load function when a configuration
+// file or source cannot be decoded. Line and Column locate the error when
+// the decoder reports it, and are 0 otherwise.
+type DecodeError struct {
+	// Name is the file or source that failed to decode.
+	Name   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	location := e.Name
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+		if e.Column > 0 {
+			location += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	return fmt.Sprintf("%v: %v", location, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RequiredFieldError errors are returned by the Load function when a field
+// tagged `required:"true"` is blank. Path is the path of the field, like
+// `DB.Replicas[1].Port`.
+type RequiredFieldError struct {
+	Path string
+}
+
+func (e *RequiredFieldError) Error() string {
+	return e.Path + " is required, but blank"
+}
+
 // New initialize a Configor
 func New(config *Config) *Configor {
 	if config == nil {
@@ -51,7 +206,11 @@ func New(config *Config) *Configor {
 		config.AutoReloadInterval = time.Second
 	}
 
-	return &Configor{Config: config}
+	if config.AutoReloadWatch && config.AutoReloadDebounce == 0 {
+		config.AutoReloadDebounce = 100 * time.Millisecond
+	}
+
+	return &Configor{Config: config, stopWatch: make(chan struct{})}
 }
 
 var testRegexp = regexp.MustCompile("_test|(\\.test$)")
@@ -85,31 +244,41 @@ func (configor *Configor) Load(config interface{}, files ...string) (err error)
 	if !defaultValue.CanAddr() {
 		return fmt.Errorf("Config %v should be addressable", config)
 	}
+	if err = configor.processFlags(config); err != nil {
+		return err
+	}
+
 	err, _ = configor.load(config, false, files...)
+	configor.current.Store(deepCopy(reflect.ValueOf(config).Elem()).Addr().Interface())
 
 	if configor.Config.AutoReload {
-		go func() {
-			timer := time.NewTimer(configor.Config.AutoReloadInterval)
-			for range timer.C {
-				reflectPtr := reflect.New(reflect.ValueOf(config).Elem().Type())
-				reflectPtr.Elem().Set(defaultValue)
-
-				var changed bool
-				if err, changed = configor.load(reflectPtr.Interface(), true, files...); err == nil && changed {
-					reflect.ValueOf(config).Elem().Set(reflectPtr.Elem())
-					if configor.Config.AutoReloadCallback != nil {
-						configor.Config.AutoReloadCallback(config)
-					}
-				} else if err != nil {
-					fmt.Printf("Failed to reload configuration from %v, got error %v\n", files, err)
-				}
-				timer.Reset(configor.Config.AutoReloadInterval)
-			}
-		}()
+		configor.watch(config, defaultValue, files)
 	}
 	return
 }
 
+// reloaded swaps in a reloaded configuration and notifies about it.
+func (configor *Configor) reloaded(config, next interface{}) {
+	prev := configor.current.Load()
+	configor.current.Store(next)
+	if configor.onReload != nil {
+		configor.onReload(next)
+	}
+
+	if configor.Config.SafeReload {
+		config = next
+	} else {
+		reflect.ValueOf(config).Elem().Set(reflect.ValueOf(next).Elem())
+	}
+	if configor.Config.AutoReloadCallback != nil {
+		configor.Config.AutoReloadCallback(config)
+	}
+
+	if fields := diffConfig(prev, next); len(fields) > 0 {
+		configor.publish(Change{Config: next, Previous: prev, Fields: fields})
+	}
+}
+
 // ENV return environment
 func ENV() string {
 	return New(nil).GetEnvironment()
@@ -0,0 +1,212 @@
+package configor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var dotenvKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// isDotenvFile reports whether file is a dotenv file, like .env or
+// .production.env
+func isDotenvFile(file string) bool {
+	return path.Ext(file) == ".env"
+}
+
+// processDotenvFile reads the variables of a dotenv file into values,
+// overwriting those read from earlier files.
+func processDotenvFile(file string, values map[string]string) error {
+	data, err := readFile(file)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			return &DecodeError{Name: file, Line: lineno, Err: fmt.Errorf("expected KEY=VALUE, got %q", line)}
+		}
+		key, value := strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:])
+		if !dotenvKeyRegexp.MatchString(key) {
+			return &DecodeError{Name: file, Line: lineno, Err: fmt.Errorf("invalid variable name %q", key)}
+		}
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			// quoted values may span multiple lines
+			quoted := value
+			end := closingQuote(quoted)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				quoted += "\n" + lines[i]
+				end = closingQuote(quoted)
+			}
+			if end < 0 {
+				return &DecodeError{Name: file, Line: lineno, Err: fmt.Errorf("unterminated quoted value for %v", key)}
+			}
+			if rest := strings.TrimSpace(quoted[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return &DecodeError{Name: file, Line: lineno, Err: fmt.Errorf("unexpected %q after quoted value for %v", rest, key)}
+			}
+
+			value = quoted[1:end]
+			if quoted[0] == '"' {
+				value = unescapeDotenv(value)
+			}
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+
+		values[key] = value
+	}
+	return nil
+}
+
+// closingQuote returns the index of the quote closing the quoted value s, or
+// -1 if it is not closed. Double quotes may be escaped with a backslash.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && s[0] == '"':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// processDotenv populates the environment with the variables read from
+// dotenv files, so processTags loads them like any other env. Variables
+// already set in the environment take precedence, unless they are blank or
+// were set from dotenv files by an earlier load.
+func (configor *Configor) processDotenv(config interface{}, values map[string]string, prefixes ...string) error {
+	if configor.GetErrorOnUnmatchedKeys() && len(values) > 0 {
+		var (
+			unmatched []string
+			matcher   = envNameRegexp(reflect.Indirect(reflect.ValueOf(config)).Type(), prefixes)
+		)
+		for key := range values {
+			if !matcher.MatchString(key) {
+				unmatched = append(unmatched, key)
+			}
+		}
+		if len(unmatched) > 0 {
+			sort.Strings(unmatched)
+			return &UnmatchedDotenvKeysError{Keys: unmatched}
+		}
+	}
+
+	for key := range configor.dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(configor.dotenvKeys, key)
+		}
+	}
+
+	for key, value := range values {
+		if os.Getenv(key) != "" && !configor.dotenvKeys[key] {
+			continue
+		}
+		if configor.dotenvKeys == nil {
+			configor.dotenvKeys = map[string]bool{}
+		}
+		os.Setenv(key, value)
+		configor.dotenvKeys[key] = true
+	}
+	return nil
+}
+
+// envNameRegexp returns a regexp matching every env name processTags reads
+// for a config of the given type.
+func envNameRegexp(configType reflect.Type, prefixes []string) *regexp.Regexp {
+	var parts []string
+	for _, prefix := range prefixes {
+		parts = append(parts, regexp.QuoteMeta(prefix))
+	}
+
+	var names []string
+	collectEnvNames(configType, parts, map[reflect.Type]bool{}, &names)
+	return regexp.MustCompile("^(?:" + strings.Join(names, "|") + ")$")
+}
+
+func collectEnvNames(configType reflect.Type, prefixes []string, seen map[reflect.Type]bool, names *[]string) {
+	for configType.Kind() == reflect.Ptr {
+		configType = configType.Elem()
+	}
+	if configType.Kind() != reflect.Struct || seen[configType] {
+		return
+	}
+	seen[configType] = true
+	defer delete(seen, configType)
+
+	for i := 0; i < configType.NumField(); i++ {
+		fieldStruct := configType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		var fieldNames []string
+		if envName := fieldStruct.Tag.Get("env"); envName != "" {
+			fieldNames = []string{regexp.QuoteMeta(envName)}
+		} else {
+			name := strings.Join(append(prefixes, regexp.QuoteMeta(fieldStruct.Name)), "_")
+			fieldNames = []string{name, strings.ToUpper(name)}
+		}
+		*names = append(*names, fieldNames...)
+
+		fieldType := fieldStruct.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		fieldPrefixes := prefixes
+		if !fieldStruct.Anonymous || fieldStruct.Tag.Get("anonymous") != "true" {
+			fieldPrefixes = append(fieldPrefixes[:len(fieldPrefixes):len(fieldPrefixes)], regexp.QuoteMeta(fieldStruct.Name))
+		}
+
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			collectEnvNames(fieldType, fieldPrefixes, seen, names)
+		case reflect.Slice:
+			collectEnvNames(fieldType.Elem(), append(fieldPrefixes[:len(fieldPrefixes):len(fieldPrefixes)], "[0-9]+"), seen, names)
+		case reflect.Map:
+			// map entries are loaded from variables named by their key
+			for _, name := range fieldNames {
+				*names = append(*names, name+"_.+")
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package configor
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// flagValue is a command-line flag bound to a config field
+type flagValue struct {
+	name     string
+	envNames []string
+	field    reflect.StructField
+	value    string
+}
+
+func (v *flagValue) String() string {
+	return v.value
+}
+
+// Set checks value can be loaded into the field, it is loaded by processTags
+func (v *flagValue) Set(value string) error {
+	if err := setFieldFromString(reflect.New(v.field.Type).Elem(), value); err != nil {
+		return err
+	}
+	v.value = value
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.field.Type.Kind() == reflect.Bool
+}
+
+// processFlags defines a flag on FlagSet for every field of config, named by
+// the lower cased path of the field like `--db.host` unless set with the
+// `flag` tag, then parses Args into them. Values of the flags that were set
+// are loaded by processTags, after shell env. Flags are parsed on the first
+// Load only, later loads reuse their values.
+func (configor *Configor) processFlags(config interface{}) error {
+	flagSet := configor.Config.FlagSet
+	if flagSet == nil || configor.flagValues != nil {
+		return nil
+	}
+
+	var values []*flagValue
+	defineFlags(flagSet, reflect.Indirect(reflect.ValueOf(config)).Type(), nil, configor.getENVPrefixes(config), &values)
+	flagSet.Usage = func() {
+		printFlagUsage(flagSet, values)
+	}
+
+	args := configor.Config.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	configor.flagValues = map[string]*flagValue{}
+	flagSet.Visit(func(f *flag.Flag) {
+		if value, ok := f.Value.(*flagValue); ok {
+			configor.flagValues[value.envNames[0]] = value
+		}
+	})
+	return nil
+}
+
+func defineFlags(flagSet *flag.FlagSet, configType reflect.Type, path, prefixes []string, values *[]*flagValue) {
+	for i := 0; i < configType.NumField(); i++ {
+		fieldStruct := configType.Field(i)
+		if fieldStruct.PkgPath != "" || fieldStruct.Tag.Get("flag") == "-" {
+			continue
+		}
+
+		fieldPath := append(path[:len(path):len(path)], strings.ToLower(fieldStruct.Name))
+		if fieldStruct.Anonymous && fieldStruct.Tag.Get("anonymous") == "true" {
+			fieldPath = path
+		}
+
+		switch fieldStruct.Type.Kind() {
+		case reflect.Struct:
+			if !isScalarType(fieldStruct.Type) {
+				defineFlags(flagSet, fieldStruct.Type, fieldPath, getPrefixForStruct(prefixes[:len(prefixes):len(prefixes)], &fieldStruct), values)
+				continue
+			}
+		case reflect.Slice:
+			if elem := fieldStruct.Type.Elem(); elem.Kind() == reflect.Struct || elem.Kind() == reflect.Ptr {
+				continue
+			}
+		case reflect.Ptr, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+			continue
+		}
+
+		value := &flagValue{name: strings.Join(fieldPath, "."), field: fieldStruct}
+		if name := fieldStruct.Tag.Get("flag"); name != "" {
+			value.name = name
+		}
+		value.envNames = getEnvNames(prefixes, &fieldStruct)
+
+		// keep the flag defined by an earlier Load on a shared FlagSet
+		if f := flagSet.Lookup(value.name); f != nil {
+			if existing, ok := f.Value.(*flagValue); ok {
+				*values = append(*values, existing)
+			}
+			continue
+		}
+
+		flagSet.Var(value, value.name, fieldStruct.Tag.Get("usage"))
+		*values = append(*values, value)
+	}
+}
+
+// printFlagUsage prints the flags bound to config fields along with their
+// env names and defaults, followed by any other flags of flagSet.
+func printFlagUsage(flagSet *flag.FlagSet, values []*flagValue) {
+	output := flagSet.Output()
+	if flagSet.Name() == "" {
+		fmt.Fprintf(output, "Usage:\n")
+	} else {
+		fmt.Fprintf(output, "Usage of %s:\n", flagSet.Name())
+	}
+
+	bound := map[string]bool{}
+	for _, value := range values {
+		bound[value.name] = true
+
+		line := "  --" + value.name
+		if !value.IsBoolFlag() {
+			line += " " + flagTypeName(value.field.Type)
+		}
+
+		details := []string{"env " + value.envNames[len(value.envNames)-1]}
+		if def := value.field.Tag.Get("default"); def != "" {
+			details = append(details, "default "+strconv.Quote(def))
+		}
+		if value.field.Tag.Get("required") == "true" {
+			details = append(details, "required")
+		}
+
+		usage := value.field.Tag.Get("usage")
+		if usage != "" {
+			usage += " "
+		}
+		fmt.Fprintf(output, "%v\n    \t%v(%v)\n", line, usage, strings.Join(details, ", "))
+	}
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if !bound[f.Name] {
+			fmt.Fprintf(output, "  --%v\n    \t%v\n", f.Name, f.Usage)
+		}
+	})
+}
+
+func flagTypeName(fieldType reflect.Type) string {
+	switch {
+	case fieldType == durationType:
+		return "duration"
+	case fieldType.Kind() == reflect.Slice:
+		return "list"
+	case fieldType.Kind() == reflect.Struct:
+		return "value"
+	}
+	return fieldType.Kind().String()
+}
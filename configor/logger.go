@@ -0,0 +1,40 @@
+package configor
+
+import (
+	"fmt"
+)
+
+// Logger receives the diagnostics of configor. It is satisfied by the
+// loggers of github.com/gleez/pkg/log, like log.NewLogrus(zerolog.Logger).
+type Logger interface {
+	// Debugf logs what configor loads in debug and verbose mode.
+	Debugf(format string, v ...interface{})
+	// Warnf logs missing configuration files and the fallback to polling
+	// when files cannot be watched, unless Silent is set.
+	Warnf(format string, v ...interface{})
+	// Errorf logs errors reloading configuration.
+	Errorf(format string, v ...interface{})
+}
+
+// stdoutLogger is the default Logger, it prints to stdout
+type stdoutLogger struct{}
+
+func (stdoutLogger) Debugf(format string, v ...interface{}) {
+	fmt.Printf(format+"\n", v...)
+}
+
+func (stdoutLogger) Warnf(format string, v ...interface{}) {
+	fmt.Printf(format+"\n", v...)
+}
+
+func (stdoutLogger) Errorf(format string, v ...interface{}) {
+	fmt.Printf(format+"\n", v...)
+}
+
+// logger returns the Logger of the configor
+func (configor *Configor) logger() Logger {
+	if configor.Config.Logger != nil {
+		return configor.Config.Logger
+	}
+	return stdoutLogger{}
+}
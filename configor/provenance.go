@@ -0,0 +1,277 @@
+package configor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"text/tabwriter"
+	"unsafe"
+)
+
+// Origin is the kind of source a configuration value was loaded from.
+type Origin string
+
+const (
+	// OriginUnset is the origin of fields that were not set.
+	OriginUnset Origin = ""
+	// OriginInitial is the origin of values set in the struct passed to Load.
+	OriginInitial Origin = "initial"
+	// OriginDefault is the origin of values set by `default` tags.
+	OriginDefault Origin = "default"
+	// OriginFile is the origin of values loaded from configuration files.
+	OriginFile Origin = "file"
+	// OriginSource is the origin of values loaded from Sources.
+	OriginSource Origin = "source"
+	// OriginEnv is the origin of values loaded from shell env.
+	OriginEnv Origin = "env"
+	// OriginFlag is the origin of values loaded from command-line flags.
+	OriginFlag Origin = "flag"
+)
+
+// Provenance describes where the value of a field was loaded from.
+type Provenance struct {
+	// Path is the path of the field, like `DB.Replicas[1].Port`.
+	Path   string
+	Origin Origin
+	// Name is the file, source, env variable or flag the value was loaded
+	// from.
+	Name string
+	// Value is the value as loaded, before references were resolved. The
+	// values of fields tagged `secret:"true"` are masked.
+	Value string
+}
+
+// ProvenanceReport lists where the value of every field was loaded from.
+type ProvenanceReport struct {
+	Fields []Provenance
+}
+
+// Lookup returns the provenance of the field at path.
+func (r *ProvenanceReport) Lookup(path string) (Provenance, bool) {
+	for _, field := range r.Fields {
+		if field.Path == path {
+			return field, true
+		}
+	}
+	return Provenance{}, false
+}
+
+// WriteTo writes the report to w as a table.
+func (r *ProvenanceReport) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	table := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "FIELD\tORIGIN\tNAME\tVALUE")
+	for _, field := range r.Fields {
+		origin := string(field.Origin)
+		if origin == "" {
+			origin = "-"
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", field.Path, origin, field.Name, field.Value)
+	}
+	table.Flush()
+	return buf.WriteTo(w)
+}
+
+func (r *ProvenanceReport) String() string {
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	return buf.String()
+}
+
+// Provenance returns where the fields of the last loaded configuration were
+// loaded from, or nil unless TrackProvenance is set.
+func (configor *Configor) Provenance() *ProvenanceReport {
+	report, _ := configor.provenance.Load().(*ProvenanceReport)
+	return report
+}
+
+type provenanceKey struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+}
+
+// provenanceTracker attributes the fields of a config being loaded to the
+// layer that set them. Loading snapshots the fields after every layer, fields
+// that changed are attributed to it. Fields set to the value they already
+// had are attributed too, to the files they are found in and to the env or
+// flag processTags recorded for them.
+type provenanceTracker struct {
+	config   interface{}
+	fields   map[string]Provenance
+	previous map[string]interface{}
+	recorded map[provenanceKey]Provenance
+}
+
+// newProvenanceTracker starts tracking config. Fields already set are
+// attributed as in the previous report, if config is being reloaded, or to
+// OriginInitial.
+func newProvenanceTracker(config interface{}, previous *ProvenanceReport) *provenanceTracker {
+	tracker := &provenanceTracker{
+		config:   config,
+		fields:   map[string]Provenance{},
+		previous: map[string]interface{}{},
+		recorded: map[provenanceKey]Provenance{},
+	}
+
+	walkLeaves("", reflect.ValueOf(config), false, func(path string, value reflect.Value, secret bool) {
+		tracker.previous[path] = deepCopy(value).Interface()
+		if value.IsZero() {
+			return
+		}
+		if previous != nil {
+			if field, ok := previous.Lookup(path); ok {
+				tracker.fields[path] = field
+				return
+			}
+		}
+		tracker.fields[path] = Provenance{Path: path, Origin: OriginInitial, Value: formatProvenanceValue(value, secret)}
+	})
+	return tracker
+}
+
+// record attributes field to origin, it is called by processTags. Like the
+// other methods it does nothing on a nil tracker.
+func (tracker *provenanceTracker) record(field reflect.Value, origin Origin, name string) {
+	if tracker == nil || !field.CanAddr() {
+		return
+	}
+	tracker.recorded[provenanceKey{field.Addr().UnsafePointer(), field.Type()}] = Provenance{Origin: origin, Name: name}
+}
+
+// decoded attributes the fields decoded from a file or source, including
+// those it sets to the value they already had, which are found by decoding
+// it into a blank config with decode.
+func (tracker *provenanceTracker) decoded(origin Origin, name string, decode func(blank interface{}) error) {
+	if tracker == nil {
+		return
+	}
+	blank := reflect.New(reflect.Indirect(reflect.ValueOf(tracker.config)).Type())
+	set := map[string]bool{}
+	if decode(blank.Interface()) == nil {
+		walkLeaves("", blank, false, func(path string, value reflect.Value, secret bool) {
+			set[path] = !value.IsZero()
+		})
+	}
+	tracker.layer(origin, name, set)
+}
+
+// layer attributes the fields that changed since the previous layer, or are
+// in set, to origin. Fields recorded by processTags are attributed to what
+// was recorded.
+func (tracker *provenanceTracker) layer(origin Origin, name string, set map[string]bool) {
+	if tracker == nil {
+		return
+	}
+	walkLeaves("", reflect.ValueOf(tracker.config), false, func(path string, value reflect.Value, secret bool) {
+		current := deepCopy(value).Interface()
+		previous, seen := tracker.previous[path]
+		tracker.previous[path] = current
+
+		field := Provenance{Path: path, Origin: origin, Name: name, Value: formatProvenanceValue(value, secret)}
+		if value.CanAddr() {
+			if recorded, ok := tracker.recorded[provenanceKey{value.Addr().UnsafePointer(), value.Type()}]; ok {
+				field.Origin, field.Name = recorded.Origin, recorded.Name
+				tracker.fields[path] = field
+				return
+			}
+		}
+		if set[path] || (seen && !reflect.DeepEqual(previous, current)) || (!seen && !value.IsZero()) {
+			tracker.fields[path] = field
+		}
+	})
+}
+
+// report returns the provenance of every field of the config
+func (tracker *provenanceTracker) report() *ProvenanceReport {
+	report := &ProvenanceReport{}
+	walkLeaves("", reflect.ValueOf(tracker.config), false, func(path string, value reflect.Value, secret bool) {
+		field, ok := tracker.fields[path]
+		if !ok {
+			field = Provenance{Path: path, Value: formatProvenanceValue(value, secret)}
+		}
+		report.Fields = append(report.Fields, field)
+	})
+	return report
+}
+
+// walkLeaves calls fn with every value of config that is reported on: fields
+// that are not structs, and the fields of structs in slices and maps.
+func walkLeaves(path string, value reflect.Value, secret bool, fn func(path string, value reflect.Value, secret bool)) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			if path != "" {
+				fn(path, value, secret)
+			}
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if hasProvenanceFields(value.Type()) {
+			valueType := value.Type()
+			for i := 0; i < valueType.NumField(); i++ {
+				fieldStruct := valueType.Field(i)
+				if fieldStruct.PkgPath != "" {
+					continue
+				}
+				name := fieldStruct.Name
+				if path != "" {
+					name = path + "." + name
+				}
+				walkLeaves(name, value.Field(i), secret || fieldStruct.Tag.Get("secret") == "true", fn)
+			}
+			return
+		}
+	case reflect.Slice, reflect.Array:
+		if hasProvenanceFields(value.Type().Elem()) {
+			for i := 0; i < value.Len(); i++ {
+				walkLeaves(fmt.Sprintf("%v[%d]", path, i), value.Index(i), secret, fn)
+			}
+			return
+		}
+	case reflect.Map:
+		if hasProvenanceFields(value.Type().Elem()) {
+			for _, key := range sortedMapKeys(value) {
+				walkLeaves(fmt.Sprintf("%v[%v]", path, key.Interface()), value.MapIndex(key), secret, fn)
+			}
+			return
+		}
+	}
+
+	if path != "" {
+		fn(path, value, secret)
+	}
+}
+
+// hasProvenanceFields reports whether the fields of valueType are reported on
+// rather than values of valueType as a whole.
+func hasProvenanceFields(valueType reflect.Type) bool {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct || isScalarType(valueType) {
+		return false
+	}
+	for i := 0; i < valueType.NumField(); i++ {
+		if valueType.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func formatProvenanceValue(value reflect.Value, secret bool) string {
+	switch {
+	case !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()):
+		return "<nil>"
+	case secret && !value.IsZero():
+		return redactedValue
+	case value.Kind() == reflect.String:
+		return strconv.Quote(value.String())
+	}
+	return fmt.Sprint(value.Interface())
+}
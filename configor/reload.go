@@ -0,0 +1,193 @@
+package configor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change describes a reloaded configuration.
+type Change struct {
+	// Config is the new configuration, a pointer of the same type as the
+	// one passed to Load. It is shared by all subscribers and must not be
+	// modified.
+	Config interface{}
+
+	// Previous is the configuration Config replaces.
+	Previous interface{}
+
+	// Fields lists the changed fields, in struct order.
+	Fields []FieldChange
+}
+
+// FieldChange is a field whose value changed on reload.
+type FieldChange struct {
+	// Path is the path of the field, like `DB.Port` or `Contacts[1].Email`.
+	Path string
+
+	Old interface{}
+	New interface{}
+}
+
+// Changed reports whether the field at path, or any field below it,
+// changed.
+func (c Change) Changed(path string) bool {
+	for _, f := range c.Fields {
+		if f.Path == path || strings.HasPrefix(f.Path, path+".") || strings.HasPrefix(f.Path, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the latest configuration loaded by Load, as a pointer of
+// the same type as the one passed to Load. Reloads never modify a returned
+// configuration, they replace it, so it can be read without locking; it
+// must not be modified. Current returns nil before Load.
+func (configor *Configor) Current() interface{} {
+	return configor.current.Load()
+}
+
+// Subscribe returns a channel receiving a Change every time a reload
+// changes the configuration, and a function to unsubscribe. A subscriber
+// which falls behind only gets the latest Change.
+func (configor *Configor) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, 1)
+
+	configor.subsMu.Lock()
+	configor.subs = append(configor.subs, ch)
+	configor.subsMu.Unlock()
+
+	return ch, func() {
+		configor.subsMu.Lock()
+		defer configor.subsMu.Unlock()
+		for i, sub := range configor.subs {
+			if sub == ch {
+				configor.subs = append(configor.subs[:i], configor.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (configor *Configor) publish(change Change) {
+	configor.subsMu.Lock()
+	defer configor.subsMu.Unlock()
+
+	for _, ch := range configor.subs {
+		select {
+		case ch <- change:
+		default:
+			// replace the change the subscriber did not pick up yet
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- change
+		}
+	}
+}
+
+// diffConfig returns the fields which differ between two configurations
+// of the same type.
+func diffConfig(old, new interface{}) []FieldChange {
+	var changes []FieldChange
+	diffValue("", reflect.ValueOf(old), reflect.ValueOf(new), &changes)
+	return changes
+}
+
+func diffValue(path string, old, new reflect.Value, changes *[]FieldChange) {
+	switch old.Kind() {
+	case reflect.Ptr:
+		if !old.IsNil() && !new.IsNil() {
+			diffValue(path, old.Elem(), new.Elem(), changes)
+			return
+		}
+	case reflect.Struct:
+		// values like time.Time are compared as a whole
+		if isScalarType(old.Type()) || !hasExportedFields(old.Type()) {
+			break
+		}
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValue(name, old.Field(i), new.Field(i), changes)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		if old.Len() == new.Len() && reflect.Indirect(reflect.New(old.Type().Elem())).Kind() == reflect.Struct {
+			for i := 0; i < old.Len(); i++ {
+				diffValue(fmt.Sprintf("%v[%d]", path, i), old.Index(i), new.Index(i), changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, FieldChange{Path: path, Old: old.Interface(), New: new.Interface()})
+	}
+}
+
+// hasExportedFields reports whether the struct type has exported fields.
+func hasExportedFields(structType reflect.Type) bool {
+	for i := 0; i < structType.NumField(); i++ {
+		if structType.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// deepCopy returns a copy of v sharing no pointers, slices or maps with it,
+// so decoding into the copy leaves v untouched.
+func deepCopy(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Type()).Elem()
+	copyValue(cp, v)
+	return cp
+}
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if !src.IsNil() {
+			dst.Set(reflect.New(src.Type().Elem()))
+			copyValue(dst.Elem(), src.Elem())
+		}
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyValue(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+			for i := 0; i < src.Len(); i++ {
+				copyValue(dst.Index(i), src.Index(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+			iter := src.MapRange()
+			for iter.Next() {
+				val := reflect.New(src.Type().Elem()).Elem()
+				copyValue(val, iter.Value())
+				dst.SetMapIndex(iter.Key(), val)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
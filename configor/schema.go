@@ -0,0 +1,318 @@
+package configor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONSchema returns a JSON Schema of the configuration files of config. It
+// includes defaults, required fields and validation tags, and the env
+// variables fields are loaded from in their descriptions.
+func (configor *Configor) JSONSchema(config interface{}) ([]byte, error) {
+	configType := reflect.TypeOf(config)
+	for configType != nil && configType.Kind() == reflect.Ptr {
+		configType = configType.Elem()
+	}
+	if configType == nil || configType.Kind() != reflect.Struct {
+		return nil, errors.New("invalid config, should be struct")
+	}
+
+	schema := configor.structSchema(configType, configor.getENVPrefixes(config), map[reflect.Type]bool{})
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if configType.Name() != "" {
+		schema["title"] = configType.Name()
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExampleYAML returns an example configuration file for config, with fields
+// set to their defaults. Comments above each field describe it with its
+// `usage` tag, and list the env variable it is loaded from and whether it is
+// required.
+func (configor *Configor) ExampleYAML(config interface{}) ([]byte, error) {
+	configType := reflect.TypeOf(config)
+	for configType != nil && configType.Kind() == reflect.Ptr {
+		configType = configType.Elem()
+	}
+	if configType == nil || configType.Kind() != reflect.Struct {
+		return nil, errors.New("invalid config, should be struct")
+	}
+
+	node, err := configor.exampleNode(configType, configor.getENVPrefixes(config), map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+	return buf.Bytes(), nil
+}
+
+// getYAMLKey returns the key of a field in YAML files, and whether the
+// fields of the struct it holds are inlined
+func getYAMLKey(fieldStruct *reflect.StructField) (key string, inline bool) {
+	name, options, _ := strings.Cut(fieldStruct.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return "", false
+	}
+	for _, option := range strings.Split(options, ",") {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(fieldStruct.Name)
+	}
+	return name, false
+}
+
+func (configor *Configor) structSchema(configType reflect.Type, prefixes []string, seen map[reflect.Type]bool) map[string]interface{} {
+	seen[configType] = true
+	defer delete(seen, configType)
+
+	var (
+		properties = map[string]interface{}{}
+		required   []string
+	)
+	for i := 0; i < configType.NumField(); i++ {
+		fieldStruct := configType.Field(i)
+		key, inline := getYAMLKey(&fieldStruct)
+		if fieldStruct.PkgPath != "" || (key == "" && !inline) {
+			continue
+		}
+
+		fieldType := fieldStruct.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if inline {
+			if fieldType.Kind() == reflect.Struct && !seen[fieldType] {
+				inlined := configor.structSchema(fieldType, getPrefixForStruct(prefixes[:len(prefixes):len(prefixes)], &fieldStruct), seen)
+				for name, property := range inlined["properties"].(map[string]interface{}) {
+					properties[name] = property
+				}
+				if names, ok := inlined["required"].([]string); ok {
+					required = append(required, names...)
+				}
+			}
+			continue
+		}
+
+		schema := configor.typeSchema(fieldType, getPrefixForStruct(prefixes[:len(prefixes):len(prefixes)], &fieldStruct), seen)
+		annotateSchema(schema, fieldType, &fieldStruct, getEnvNames(prefixes, &fieldStruct))
+		properties[key] = schema
+		if fieldStruct.Tag.Get("required") == "true" {
+			required = append(required, key)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (configor *Configor) typeSchema(valueType reflect.Type, prefixes []string, seen map[reflect.Type]bool) map[string]interface{} {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	switch {
+	case valueType == durationType:
+		return map[string]interface{}{"type": "string", "format": "duration"}
+	case valueType == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case valueType == urlType:
+		return map[string]interface{}{"type": "string", "format": "uri"}
+	case isScalarType(valueType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": configor.typeSchema(valueType.Elem(), append(prefixes[:len(prefixes):len(prefixes)], "<N>"), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": configor.typeSchema(valueType.Elem(), prefixes, seen)}
+	case reflect.Struct:
+		if !seen[valueType] {
+			return configor.structSchema(valueType, prefixes, seen)
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// annotateSchema adds the description, default and validations of a field to
+// its schema
+func annotateSchema(schema map[string]interface{}, fieldType reflect.Type, fieldStruct *reflect.StructField, envNames []string) {
+	description := fieldStruct.Tag.Get("usage")
+	if description != "" {
+		description += " "
+	}
+	schema["description"] = description + "(env " + envNames[len(envNames)-1] + ")"
+
+	if value := fieldStruct.Tag.Get("default"); value != "" {
+		schema["default"] = schemaValue(fieldType, value)
+	}
+	if fieldStruct.Tag.Get("secret") == "true" {
+		schema["writeOnly"] = true
+	}
+
+	bounds := map[string][2]string{
+		"integer": {"minimum", "maximum"},
+		"number":  {"minimum", "maximum"},
+		"string":  {"minLength", "maxLength"},
+		"array":   {"minItems", "maxItems"},
+		"object":  {"minProperties", "maxProperties"},
+	}
+	if keywords, ok := bounds[schema["type"].(string)]; ok && fieldType != durationType {
+		for i, tag := range []string{"min", "max"} {
+			if bound, err := strconv.ParseFloat(fieldStruct.Tag.Get(tag), 64); err == nil {
+				schema[keywords[i]] = bound
+			}
+		}
+	}
+
+	if pattern := fieldStruct.Tag.Get("regexp"); pattern != "" {
+		schema["pattern"] = pattern
+	}
+	if oneof := fieldStruct.Tag.Get("oneof"); oneof != "" {
+		var enum []interface{}
+		for _, option := range strings.Fields(oneof) {
+			enum = append(enum, schemaValue(fieldType, option))
+		}
+		schema["enum"] = enum
+	}
+	if fieldStruct.Tag.Get("format") == "url" {
+		schema["format"] = "uri"
+	}
+}
+
+// schemaValue returns the value of a tag as it is written in configuration
+// files
+func schemaValue(valueType reflect.Type, value string) interface{} {
+	if isScalarType(valueType) {
+		return value
+	}
+	decoded := reflect.New(valueType)
+	if err := yaml.Unmarshal([]byte(value), decoded.Interface()); err != nil {
+		return value
+	}
+	return decoded.Elem().Interface()
+}
+
+func (configor *Configor) exampleNode(configType reflect.Type, prefixes []string, seen map[reflect.Type]bool) (*yaml.Node, error) {
+	seen[configType] = true
+	defer delete(seen, configType)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < configType.NumField(); i++ {
+		fieldStruct := configType.Field(i)
+		key, inline := getYAMLKey(&fieldStruct)
+		if fieldStruct.PkgPath != "" || (key == "" && !inline) {
+			continue
+		}
+
+		fieldType := fieldStruct.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		fieldPrefixes := getPrefixForStruct(prefixes[:len(prefixes):len(prefixes)], &fieldStruct)
+
+		if inline {
+			if fieldType.Kind() == reflect.Struct && !seen[fieldType] {
+				inlined, err := configor.exampleNode(fieldType, fieldPrefixes, seen)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, inlined.Content...)
+			}
+			continue
+		}
+
+		var comments []string
+		if usage := fieldStruct.Tag.Get("usage"); usage != "" {
+			comments = append(comments, usage)
+		}
+		envNames := getEnvNames(prefixes, &fieldStruct)
+		comments = append(comments, "env: "+envNames[len(envNames)-1])
+		if fieldStruct.Tag.Get("required") == "true" {
+			comments = append(comments, "required")
+		}
+
+		value, err := configor.exampleValue(fieldType, fieldPrefixes, fieldStruct.Tag.Get("default"), seen)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key, HeadComment: strings.Join(comments, "\n")},
+			value,
+		)
+	}
+	return node, nil
+}
+
+// exampleValue returns the example value of a field, its default or an
+// example of its nested fields
+func (configor *Configor) exampleValue(valueType reflect.Type, prefixes []string, defaultValue string, seen map[reflect.Type]bool) (*yaml.Node, error) {
+	value := reflect.New(valueType)
+	if defaultValue != "" {
+		if err := yaml.Unmarshal([]byte(defaultValue), value.Interface()); err != nil {
+			return nil, err
+		}
+	} else if !isScalarType(valueType) {
+		switch valueType.Kind() {
+		case reflect.Struct:
+			if !seen[valueType] {
+				return configor.exampleNode(valueType, prefixes, seen)
+			}
+		case reflect.Slice:
+			elemType := valueType.Elem()
+			for elemType.Kind() == reflect.Ptr {
+				elemType = elemType.Elem()
+			}
+			if elemType.Kind() == reflect.Struct && !isScalarType(elemType) && !seen[elemType] {
+				elem, err := configor.exampleNode(elemType, append(prefixes[:len(prefixes):len(prefixes)], "0"), seen)
+				if err != nil {
+					return nil, err
+				}
+				return &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{elem}}, nil
+			}
+		}
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(value.Elem().Interface()); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
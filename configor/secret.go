@@ -0,0 +1,118 @@
+package configor
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Resolver resolves the key of references like `${vault:db/password}` in
+// configuration values to the value they stand for.
+type Resolver interface {
+	Resolve(key string) (string, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(key string) (string, error)
+
+// Resolve calls f(key)
+func (f ResolverFunc) Resolve(key string) (string, error) {
+	return f(key)
+}
+
+// EnvResolver resolves `${env:NAME}` references to the value of environment
+// variable NAME.
+var EnvResolver = ResolverFunc(func(key string) (string, error) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("environment variable %v is not set", key)
+})
+
+// FileResolver resolves `${file:/run/secrets/db}` references to the content
+// of the file, without trailing newlines.
+var FileResolver = ResolverFunc(func(key string) (string, error) {
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+})
+
+var referenceRegexp = regexp.MustCompile(`\$\{([A-Za-z][A-Za-z0-9_-]*):([^}]*)\}`)
+
+const redactedValue = "******"
+
+// resolver returns the resolver for references named name
+func (configor *Configor) resolver(name string) Resolver {
+	if resolver, ok := configor.Config.Resolvers[name]; ok {
+		return resolver
+	}
+	switch name {
+	case "env":
+		return EnvResolver
+	case "file":
+		return FileResolver
+	}
+	return nil
+}
+
+// processReferences replaces the references in the string values of config
+// with what they resolve to. References named after no resolver, like
+// `${HOME:-/tmp}` in a shell command, are left untouched.
+func (configor *Configor) processReferences(config interface{}) error {
+	return walkFields("", reflect.ValueOf(config), "", func(path string, value reflect.Value, tag reflect.StructTag) error {
+		if value.Kind() != reflect.String || !value.CanSet() || !strings.Contains(value.String(), "${") {
+			return nil
+		}
+
+		var err error
+		resolved := referenceRegexp.ReplaceAllStringFunc(value.String(), func(reference string) string {
+			match := referenceRegexp.FindStringSubmatch(reference)
+			resolver := configor.resolver(match[1])
+			if resolver == nil {
+				return reference
+			}
+
+			result, resolveErr := resolver.Resolve(match[2])
+			if resolveErr != nil && err == nil {
+				err = fmt.Errorf("%v: failed to resolve %v: %w", path, reference, resolveErr)
+			}
+			return result
+		})
+		if err != nil || resolved == value.String() {
+			return err
+		}
+
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Debugf("Resolved references of field `%v`", path)
+		}
+		value.SetString(resolved)
+		return nil
+	})
+}
+
+// redact returns a copy of config with the fields tagged `secret:"true"`
+// masked, to be printed instead of config.
+func redact(config interface{}) interface{} {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.IsNil() || configValue.Elem().Kind() != reflect.Struct {
+		return config
+	}
+
+	redacted := deepCopy(configValue.Elem())
+	walkFields("", redacted, "", func(path string, value reflect.Value, tag reflect.StructTag) error {
+		if tag.Get("secret") != "true" || value.IsZero() || !value.CanSet() {
+			return nil
+		}
+		if value.Kind() == reflect.String {
+			value.SetString(redactedValue)
+		} else {
+			value.Set(reflect.Zero(value.Type()))
+		}
+		return nil
+	})
+	return redacted.Addr().Interface()
+}
@@ -0,0 +1,185 @@
+package configor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotModified is returned by Source.Fetch when the configuration of the
+// source is still at the version it was passed.
+var ErrNotModified = errors.New("configor: source not modified")
+
+// Source is a source of configuration other than files, like an HTTP
+// endpoint or a key-value store. Sources are loaded after files, so they
+// take precedence over them, and like files earlier sources take precedence
+// over later ones. Auto reload polls sources for new versions.
+type Source interface {
+	// Name identifies the source in messages and provenance reports.
+	Name() string
+	// Fetch returns the configuration of the source. It is passed the
+	// version fetched before, or "" to fetch it regardless, and returns
+	// ErrNotModified if the configuration is still at that version.
+	Fetch(ctx context.Context, version string) (*SourceData, error)
+}
+
+// SourceData is configuration fetched from a Source.
+type SourceData struct {
+	Data []byte
+	// Format is "yaml", "json" or "toml", it is detected if blank.
+	Format string
+	// Version identifies the content of Data, like an ETag.
+	Version string
+}
+
+// HTTPSource fetches configuration from an HTTP endpoint. Its version is the
+// ETag of the response, or a hash of the response body if there is none.
+type HTTPSource struct {
+	URL string
+	// Header is sent with every request, like an Authorization header.
+	Header http.Header
+	// Format of the response, detected from its Content-Type or the
+	// extension of URL if blank.
+	Format string
+	// Client sends the requests, an http.Client with a 30 second timeout
+	// by default.
+	Client *http.Client
+}
+
+var defaultHTTPSourceClient = &http.Client{Timeout: 30 * time.Second}
+
+// Name returns the URL of the source
+func (source *HTTPSource) Name() string {
+	return source.URL
+}
+
+// Fetch requests the configuration, conditionally if version is an ETag
+func (source *HTTPSource) Fetch(ctx context.Context, version string) (*SourceData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range source.Header {
+		req.Header[key] = values
+	}
+	if version != "" && !strings.HasPrefix(version, "sha256:") {
+		req.Header.Set("If-None-Match", version)
+	}
+
+	client := source.Client
+	if client == nil {
+		client = defaultHTTPSourceClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response %v", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched := &SourceData{Data: data, Format: source.Format, Version: resp.Header.Get("ETag")}
+	if fetched.Version == "" {
+		sum := sha256.Sum256(data)
+		fetched.Version = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	if version != "" && fetched.Version == version {
+		return nil, ErrNotModified
+	}
+
+	if fetched.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch {
+		case strings.Contains(mediaType, "json"):
+			fetched.Format = "json"
+		case strings.Contains(mediaType, "yaml") || strings.Contains(mediaType, "yml"):
+			fetched.Format = "yaml"
+		case strings.Contains(mediaType, "toml"):
+			fetched.Format = "toml"
+		default:
+			if u, err := url.Parse(source.URL); err == nil {
+				fetched.Format = getFormat(u.Path)
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// KVStore is a key-value store configuration is read from, like etcd or
+// Consul.
+type KVStore interface {
+	// Get returns the value of key and its version, like a modification
+	// index.
+	Get(ctx context.Context, key string) (value []byte, version string, err error)
+}
+
+// KVSource loads configuration from the value of a key in a KVStore.
+type KVSource struct {
+	Store KVStore
+	Key   string
+	// Format of the value, detected if blank.
+	Format string
+}
+
+// Name returns the key of the source
+func (source *KVSource) Name() string {
+	return "kv:" + source.Key
+}
+
+// Fetch gets the value of the key
+func (source *KVSource) Fetch(ctx context.Context, version string) (*SourceData, error) {
+	value, current, err := source.Store.Get(ctx, source.Key)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && current == version {
+		return nil, ErrNotModified
+	}
+	return &SourceData{Data: value, Format: source.Format, Version: current}, nil
+}
+
+// fetchSources fetches the configuration of every source. In watch mode
+// only newer versions than the ones loaded before are fetched, changed
+// reports whether there were any.
+func (configor *Configor) fetchSources(watchMode bool) (fetched []*SourceData, changed bool, err error) {
+	for i, source := range configor.Config.Sources {
+		var previous *SourceData
+		if watchMode && i < len(configor.sourceData) {
+			previous = configor.sourceData[i]
+		}
+
+		version := ""
+		if previous != nil {
+			version = previous.Version
+		}
+
+		data, err := source.Fetch(context.Background(), version)
+		if errors.Is(err, ErrNotModified) && previous != nil {
+			fetched = append(fetched, previous)
+			continue
+		} else if err != nil {
+			return nil, false, fmt.Errorf("failed to fetch configuration from %v: %w", source.Name(), err)
+		}
+		fetched = append(fetched, data)
+		changed = true
+	}
+	return fetched, changed, nil
+}
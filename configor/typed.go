@@ -0,0 +1,69 @@
+package configor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LoadAs loads a new T from files, like Load.
+func LoadAs[T any](config *Config, files ...string) (*T, error) {
+	result := new(T)
+	if err := New(config).Load(result, files...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Watcher holds a configuration of type T that is reloaded when its files or
+// sources change. Reloaded configurations are swapped in atomically, so hot
+// paths can read them with Current without locking.
+type Watcher[T any] struct {
+	configor  *Configor
+	current   atomic.Pointer[T]
+	closeOnce sync.Once
+}
+
+// Watch loads a new T from files and keeps reloading it, config is used
+// with AutoReload and SafeReload set.
+func Watch[T any](config *Config, files ...string) (*Watcher[T], error) {
+	var options Config
+	if config != nil {
+		options = *config
+	}
+	options.AutoReload = true
+	options.SafeReload = true
+
+	watcher := &Watcher[T]{configor: New(&options)}
+	watcher.configor.onReload = func(next interface{}) {
+		watcher.current.Store(next.(*T))
+	}
+
+	// the loaded config is left alone by SafeReload, so it is safe to share
+	loaded := new(T)
+	watcher.current.Store(loaded)
+	if err := watcher.configor.Load(loaded, files...); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Current returns the latest configuration. It must not be modified.
+func (watcher *Watcher[T]) Current() *T {
+	return watcher.current.Load()
+}
+
+// Close stops reloading the configuration and closes the watched files.
+// Current keeps returning the latest configuration.
+func (watcher *Watcher[T]) Close() {
+	watcher.closeOnce.Do(func() {
+		close(watcher.configor.stopWatch)
+	})
+	watcher.configor.watching.Wait()
+}
+
+// Subscribe returns a channel receiving the changes of every reload, see
+// Configor.Subscribe. Change.Config and Change.Previous hold a *T.
+func (watcher *Watcher[T]) Subscribe() (<-chan Change, func()) {
+	return watcher.configor.Subscribe()
+}
@@ -2,16 +2,23 @@ package configor
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
@@ -25,30 +32,32 @@ func (configor *Configor) getENVPrefix(config interface{}) string {
 	return configor.Config.ENVPrefix
 }
 
-func getConfigurationFileWithENVPrefix(file, env string) (string, time.Time, error) {
-	var (
-		envFile string
-		extname = path.Ext(file)
-	)
-
+// getConfigurationFileNameWithENVPrefix returns the name of the env specific
+// variant of file, e.g. config.production.yml for config.yml
+func getConfigurationFileNameWithENVPrefix(file, env string) string {
+	extname := path.Ext(file)
 	if extname == "" {
-		envFile = fmt.Sprintf("%v.%v", file, env)
-	} else {
-		envFile = fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+		return fmt.Sprintf("%v.%v", file, env)
 	}
+	return fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+}
 
+func getConfigurationFileWithENVPrefix(file, env string) (string, time.Time, error) {
+	envFile := getConfigurationFileNameWithENVPrefix(file, env)
 	if fileInfo, err := os.Stat(envFile); err == nil && fileInfo.Mode().IsRegular() {
 		return envFile, fileInfo.ModTime(), nil
 	}
 	return "", time.Now(), fmt.Errorf("failed to find file %v", file)
 }
 
-func (configor *Configor) getConfigurationFiles(watchMode bool, files ...string) ([]string, map[string]time.Time) {
-	var resultKeys []string
+// getConfigurationFiles returns the configuration files found for files,
+// with their modification times, and the files that were not found.
+func (configor *Configor) getConfigurationFiles(watchMode bool, files ...string) ([]string, map[string]time.Time, []string) {
+	var resultKeys, missing []string
 	var results = map[string]time.Time{}
 
 	if !watchMode && (configor.Config.Debug || configor.Config.Verbose) {
-		fmt.Printf("Current environment: '%v'\n", configor.GetEnvironment())
+		configor.logger().Debugf("Current environment: '%v'", configor.GetEnvironment())
 	}
 
 	for i := len(files) - 1; i >= 0; i-- {
@@ -73,33 +82,133 @@ func (configor *Configor) getConfigurationFiles(watchMode bool, files ...string)
 		if !foundFile {
 			if example, modTime, err := getConfigurationFileWithENVPrefix(file, "example"); err == nil {
 				if !watchMode && !configor.Silent {
-					fmt.Printf("Failed to find configuration %v, using example file %v\n", file, example)
+					configor.logger().Warnf("Failed to find configuration %v, using example file %v", file, example)
 				}
 				resultKeys = append(resultKeys, example)
 				results[example] = modTime
-			} else if !configor.Silent {
-				fmt.Printf("Failed to find configuration %v\n", file)
+			} else {
+				if !configor.Silent {
+					configor.logger().Warnf("Failed to find configuration %v", file)
+				}
+				missing = append(missing, file)
 			}
 		}
 	}
-	return resultKeys, results
+	return resultKeys, results, missing
 }
 
-func processFile(config interface{}, file string, errorOnUnmatchedKeys bool) error {
+// readFile reads a configuration file, returning a *FileNotFoundError if it
+// does not exist
+func readFile(file string) ([]byte, error) {
 	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &FileNotFoundError{File: file}
+	}
+	return data, err
+}
+
+func processFile(config interface{}, file string, errorOnUnmatchedKeys bool) error {
+	data, err := readFile(file)
 	if err != nil {
 		return err
 	}
 
+	if err := processData(config, data, getFormat(file), errorOnUnmatchedKeys); err != nil {
+		return newDecodeError(file, data, err)
+	}
+	return nil
+}
+
+var decodeLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// newDecodeError returns a *DecodeError for err, the error decoding data
+// from name, locating it in data when the decoder reports where it is.
+// Unmatched keys are not decode errors and are returned as they are.
+func newDecodeError(name string, data []byte, err error) error {
+	if isUnmatchedKeysError(err) {
+		return err
+	}
+
+	var (
+		decodeErr = &DecodeError{Name: name, Err: err}
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		tomlErr   toml.ParseError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		decodeErr.Line, decodeErr.Column = lineColumn(data, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		decodeErr.Line, decodeErr.Column = lineColumn(data, typeErr.Offset)
+	case errors.As(err, &tomlErr):
+		decodeErr.Line, decodeErr.Column = tomlErr.Position.Line, tomlErr.Position.Col
+	default:
+		// yaml reports errors like `yaml: line 3: did not find expected key`
+		if match := decodeLineRegexp.FindStringSubmatch(err.Error()); match != nil {
+			decodeErr.Line, _ = strconv.Atoi(match[1])
+		}
+	}
+	return decodeErr
+}
+
+// isUnmatchedKeysError reports whether err is the error of a decoder about
+// keys that do not match any field, with ErrorOnUnmatchedKeys set
+func isUnmatchedKeysError(err error) bool {
+	var (
+		tomlErr *UnmatchedTomlKeysError
+		yamlErr *yaml.TypeError
+	)
+	if errors.As(err, &tomlErr) {
+		return true
+	}
+	if errors.As(err, &yamlErr) {
+		for _, message := range yamlErr.Errors {
+			if !strings.Contains(message, "not found in type") {
+				return false
+			}
+		}
+		return true
+	}
+	return strings.Contains(err.Error(), "json: unknown field")
+}
+
+// lineColumn returns the line and column of offset in data, both starting
+// at 1
+func lineColumn(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	return bytes.Count(before, []byte("\n")) + 1, int(offset) - bytes.LastIndexByte(before, '\n')
+}
+
+// getFormat returns the format of a configuration file by its extension
+func getFormat(file string) string {
 	switch {
 	case strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml"):
+		return "yaml"
+	case strings.HasSuffix(file, ".toml"):
+		return "toml"
+	case strings.HasSuffix(file, ".json"):
+		return "json"
+	}
+	return ""
+}
+
+// processData decodes configuration data in format, which is detected if
+// blank, into config
+func processData(config interface{}, data []byte, format string, errorOnUnmatchedKeys bool) error {
+	switch format {
+	case "yaml":
 		if errorOnUnmatchedKeys {
 			decoder := yaml.NewDecoder(bytes.NewBuffer(data))
 			decoder.KnownFields(true)
 			return decoder.Decode(config)
 		}
 		return yaml.Unmarshal(data, config)
-	case strings.HasSuffix(file, ".json"):
+	case "toml":
+		return unmarshalToml(data, config, errorOnUnmatchedKeys)
+	case "json":
 		return unmarshalJSON(data, config, errorOnUnmatchedKeys)
 	default:
 		if err := unmarshalJSON(data, config, errorOnUnmatchedKeys); err == nil {
@@ -108,6 +217,12 @@ func processFile(config interface{}, file string, errorOnUnmatchedKeys bool) err
 			return err
 		}
 
+		if err := unmarshalToml(data, config, errorOnUnmatchedKeys); err == nil {
+			return nil
+		} else if errUnmatchedKeys, ok := err.(*UnmatchedTomlKeysError); ok {
+			return errUnmatchedKeys
+		}
+
 		var yamlError error
 		if errorOnUnmatchedKeys {
 			decoder := yaml.NewDecoder(bytes.NewBuffer(data))
@@ -127,6 +242,18 @@ func processFile(config interface{}, file string, errorOnUnmatchedKeys bool) err
 	}
 }
 
+// unmarshalToml unmarshals the given data into the config interface.
+// If the errorOnUnmatchedKeys boolean is true, an UnmatchedTomlKeysError is
+// returned if there are keys in the data that do not match fields in the
+// config interface.
+func unmarshalToml(data []byte, config interface{}, errorOnUnmatchedKeys bool) error {
+	metadata, err := toml.Decode(string(data), config)
+	if err == nil && len(metadata.Undecoded()) > 0 && errorOnUnmatchedKeys {
+		return &UnmatchedTomlKeysError{Keys: metadata.Undecoded()}
+	}
+	return err
+}
+
 // unmarshalJSON unmarshals the given data into the config interface.
 // If the errorOnUnmatchedKeys boolean is true, an error will be returned if there
 // are keys in the data that do not match fields in the config interface.
@@ -145,6 +272,14 @@ func unmarshalJSON(data []byte, config interface{}, errorOnUnmatchedKeys bool) e
 	return nil
 }
 
+// getENVPrefixes returns the prefixes of the env names of config fields
+func (configor *Configor) getENVPrefixes(config interface{}) []string {
+	if prefix := configor.getENVPrefix(config); prefix != "-" {
+		return []string{prefix}
+	}
+	return nil
+}
+
 func getPrefixForStruct(prefixes []string, fieldStruct *reflect.StructField) []string {
 	if fieldStruct.Anonymous && fieldStruct.Tag.Get("anonymous") == "true" {
 		return prefixes
@@ -152,6 +287,18 @@ func getPrefixForStruct(prefixes []string, fieldStruct *reflect.StructField) []s
 	return append(prefixes, fieldStruct.Name)
 }
 
+// getEnvNames returns the names of the shell env a field is loaded from
+func getEnvNames(prefixes []string, fieldStruct *reflect.StructField) []string {
+	if envName := fieldStruct.Tag.Get("env"); envName != "" { // read configuration from shell env
+		return []string{envName}
+	}
+	name := strings.Join(append(prefixes[:len(prefixes):len(prefixes)], fieldStruct.Name), "_")
+	return []string{
+		name,                  // Configor_DB_Name
+		strings.ToUpper(name), // CONFIGOR_DB_NAME
+	}
+}
+
 func (configor *Configor) processDefaults(config interface{}) error {
 	configValue := reflect.Indirect(reflect.ValueOf(config))
 	if configValue.Kind() != reflect.Struct {
@@ -201,7 +348,170 @@ func (configor *Configor) processDefaults(config interface{}) error {
 	return nil
 }
 
-func (configor *Configor) processTags(config interface{}, prefixes ...string) error {
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	urlType  = reflect.TypeOf(url.URL{})
+)
+
+// isScalarType reports whether values of valueType are written as scalars,
+// even though they may be structs
+func isScalarType(valueType reflect.Type) bool {
+	return valueType == durationType || valueType == timeType || valueType == urlType || reflect.PtrTo(valueType).Implements(textUnmarshalerType)
+}
+
+// setFieldFromString sets field from the string value of an env or flag.
+// Besides scalars it decodes durations, URLs, encoding.TextUnmarshaler types
+// like net.IP, comma separated slices like `a,b` and maps like `a:1,b:2`.
+// Other values, and slices and maps written in YAML like `[a, b]`, `{a: 1}`
+// or over multiple lines, are decoded as YAML.
+func setFieldFromString(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setFieldFromString(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	switch field.Type() {
+	case durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case urlType:
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(value)
+	switch field.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "", "0", "f", "false":
+			field.SetBool(false)
+		default:
+			field.SetBool(true)
+		}
+		return nil
+	case reflect.String:
+		field.SetString(value)
+		return nil
+	case reflect.Slice:
+		if isScalarType(field.Type().Elem()) || !isCompositeKind(field.Type().Elem()) {
+			if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "- ") || strings.Contains(trimmed, "\n") {
+				break
+			}
+			var items []string
+			if trimmed != "" {
+				items = strings.Split(value, ",")
+			}
+			slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+			for i, item := range items {
+				if err := setFieldFromString(slice.Index(i), strings.TrimSpace(item)); err != nil {
+					return fmt.Errorf("invalid item %q: %w", item, err)
+				}
+			}
+			field.Set(slice)
+			return nil
+		}
+	case reflect.Map:
+		if isScalarType(field.Type().Elem()) || !isCompositeKind(field.Type().Elem()) {
+			if strings.HasPrefix(trimmed, "{") || strings.Contains(trimmed, "\n") {
+				break
+			}
+			entries := reflect.MakeMap(field.Type())
+			if trimmed != "" {
+				for _, entry := range strings.Split(value, ",") {
+					key, item, ok := strings.Cut(entry, ":")
+					if !ok {
+						return fmt.Errorf("expected key:value, got %q", entry)
+					}
+					if err := setMapIndexFromString(entries, strings.TrimSpace(key), strings.TrimSpace(item)); err != nil {
+						return err
+					}
+				}
+			}
+			field.Set(entries)
+			return nil
+		}
+	}
+	return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+}
+
+// setMapIndexFromString sets the entry of key in entries from the string
+// values of its key and value
+func setMapIndexFromString(entries reflect.Value, key, value string) error {
+	mapKey := reflect.New(entries.Type().Key()).Elem()
+	if err := setFieldFromString(mapKey, key); err != nil {
+		return fmt.Errorf("invalid key %q: %w", key, err)
+	}
+	mapValue := reflect.New(entries.Type().Elem()).Elem()
+	if err := setFieldFromString(mapValue, value); err != nil {
+		return fmt.Errorf("invalid value of %q: %w", key, err)
+	}
+	entries.SetMapIndex(mapKey, mapValue)
+	return nil
+}
+
+// isCompositeKind reports whether values of valueType are made of other
+// values, which are not written in comma separated lists
+func isCompositeKind(valueType reflect.Type) bool {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	switch valueType.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// processMapEnv loads the entries of a map field from the env named by one
+// of its envNames and their key, like CONFIGOR_LABELS_TEAM for key TEAM.
+func (configor *Configor) processMapEnv(field reflect.Value, envNames []string) error {
+	environ := os.Environ()
+	sort.Strings(environ)
+
+	loaded := map[string]bool{}
+	for _, envName := range envNames {
+		for _, variable := range environ {
+			name, value, _ := strings.Cut(variable, "=")
+			key := strings.TrimPrefix(name, envName+"_")
+			if key == name || key == "" || value == "" || loaded[name] {
+				continue
+			}
+			loaded[name] = true
+
+			if configor.Config.Debug || configor.Config.Verbose {
+				configor.logger().Debugf("Loading configuration for map entry `%v` from env %v...", key, name)
+			}
+			if field.IsNil() {
+				field.Set(reflect.MakeMap(field.Type()))
+			}
+			if err := setMapIndexFromString(field, key, value); err != nil {
+				return fmt.Errorf("failed to load env %v: %w", name, err)
+			}
+			configor.tracking.record(field, OriginEnv, name)
+		}
+	}
+	return nil
+}
+
+// processTags loads the fields of config from shell env and flags, and
+// checks required fields are set. path is the path of config, like `DB`.
+func (configor *Configor) processTags(config interface{}, path string, prefixes ...string) error {
 	configValue := reflect.Indirect(reflect.ValueOf(config))
 	if configValue.Kind() != reflect.Struct {
 		return errors.New("invalid config, should be struct")
@@ -213,53 +523,57 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 			envNames    []string
 			fieldStruct = configType.Field(i)
 			field       = configValue.Field(i)
-			envName     = fieldStruct.Tag.Get("env") // read configuration from shell env
 		)
 
 		if !field.CanAddr() || !field.CanInterface() {
 			continue
 		}
 
-		if envName == "" {
-			envNames = append(envNames, strings.Join(append(prefixes, fieldStruct.Name), "_"))                  // Configor_DB_Name
-			envNames = append(envNames, strings.ToUpper(strings.Join(append(prefixes, fieldStruct.Name), "_"))) // CONFIGOR_DB_NAME
-		} else {
-			envNames = []string{envName}
+		envNames = getEnvNames(prefixes, &fieldStruct)
+		fieldPath := fieldStruct.Name
+		if path != "" {
+			fieldPath = path + "." + fieldPath
 		}
 
 		if configor.Config.Verbose {
-			fmt.Printf("Trying to load struct `%v`'s field `%v` from env %v\n", configType.Name(), fieldStruct.Name, strings.Join(envNames, ", "))
+			configor.logger().Debugf("Trying to load struct `%v`'s field `%v` from env %v", configType.Name(), fieldStruct.Name, strings.Join(envNames, ", "))
 		}
 
 		// Load From Shell ENV
 		for _, env := range envNames {
 			if value := os.Getenv(env); value != "" {
 				if configor.Config.Debug || configor.Config.Verbose {
-					fmt.Printf("Loading configuration for struct `%v`'s field `%v` from env %v...\n", configType.Name(), fieldStruct.Name, env)
+					configor.logger().Debugf("Loading configuration for struct `%v`'s field `%v` from env %v...", configType.Name(), fieldStruct.Name, env)
 				}
 
-				switch reflect.Indirect(field).Kind() {
-				case reflect.Bool:
-					switch strings.ToLower(value) {
-					case "", "0", "f", "false":
-						field.Set(reflect.ValueOf(false))
-					default:
-						field.Set(reflect.ValueOf(true))
-					}
-				case reflect.String:
-					field.Set(reflect.ValueOf(value))
-				default:
-					if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
-						return err
-					}
+				if err := setFieldFromString(field, value); err != nil {
+					return fmt.Errorf("failed to load env %v: %w", env, err)
 				}
+				configor.tracking.record(field, OriginEnv, env)
 				break
 			}
 		}
+		if field.Kind() == reflect.Map {
+			if err := configor.processMapEnv(field, envNames); err != nil {
+				return err
+			}
+		}
+
+		// Load From Command-line Flags
+		if flag, ok := configor.flagValues[envNames[0]]; ok {
+			if configor.Config.Debug || configor.Config.Verbose {
+				configor.logger().Debugf("Loading configuration for struct `%v`'s field `%v` from flag --%v...", configType.Name(), fieldStruct.Name, flag.name)
+			}
+
+			if err := setFieldFromString(field, flag.value); err != nil {
+				return err
+			}
+			configor.tracking.record(field, OriginFlag, "--"+flag.name)
+		}
 
 		if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank && fieldStruct.Tag.Get("required") == "true" {
 			// return error if it is required but blank
-			return errors.New(fieldStruct.Name + " is required, but blank")
+			return &RequiredFieldError{Path: fieldPath}
 		}
 
 		for field.Kind() == reflect.Ptr {
@@ -267,7 +581,7 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 		}
 
 		if field.Kind() == reflect.Struct {
-			if err := configor.processTags(field.Addr().Interface(), getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
+			if err := configor.processTags(field.Addr().Interface(), fieldPath, getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
 				return err
 			}
 		}
@@ -276,7 +590,7 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 			if arrLen := field.Len(); arrLen > 0 {
 				for i := 0; i < arrLen; i++ {
 					if reflect.Indirect(field.Index(i)).Kind() == reflect.Struct {
-						if err := configor.processTags(field.Index(i).Addr().Interface(), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(i))...); err != nil {
+						if err := configor.processTags(field.Index(i).Addr().Interface(), fmt.Sprintf("%v[%d]", fieldPath, i), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(i))...); err != nil {
 							return err
 						}
 					}
@@ -288,7 +602,7 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 					idx := 0
 					for {
 						newVal = reflect.New(field.Type().Elem()).Elem()
-						if err := configor.processTags(newVal.Addr().Interface(), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(idx))...); err != nil {
+						if err := configor.processTags(newVal.Addr().Interface(), fmt.Sprintf("%v[%d]", fieldPath, idx), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(idx))...); err != nil {
 							return err
 						} else if reflect.DeepEqual(newVal.Interface(), reflect.New(field.Type().Elem()).Elem().Interface()) {
 							break
@@ -304,20 +618,90 @@ func (configor *Configor) processTags(config interface{}, prefixes ...string) er
 	return nil
 }
 
+// walkFields calls fn with every field of value, and every element of its
+// slices, arrays and maps, along with its path like `DB.Replicas[1].Port`.
+// Pointers are followed, and values stored in maps are written back after
+// fn returns.
+func walkFields(path string, value reflect.Value, tag reflect.StructTag, fn func(path string, value reflect.Value, tag reflect.StructTag) error) error {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if path != "" {
+		if err := fn(path, value, tag); err != nil {
+			return err
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			fieldStruct := valueType.Field(i)
+			if fieldStruct.PkgPath != "" {
+				continue
+			}
+			name := fieldStruct.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			if err := walkFields(name, value.Field(i), fieldStruct.Tag, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := walkFields(fmt.Sprintf("%v[%d]", path, i), value.Index(i), "", fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range sortedMapKeys(value) {
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			if err := walkFields(fmt.Sprintf("%v[%v]", path, key.Interface()), elem, "", fn); err != nil {
+				return err
+			}
+			value.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
+
+// sortedMapKeys returns the keys of a map value in a stable order
+func sortedMapKeys(value reflect.Value) []reflect.Value {
+	keys := value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
 func (configor *Configor) load(config interface{}, watchMode bool, files ...string) (err error, changed bool) {
 	defer func() {
 		if configor.Config.Debug || configor.Config.Verbose {
 			if err != nil {
-				fmt.Printf("Failed to load configuration from %v, got %v\n", files, err)
+				configor.logger().Debugf("Failed to load configuration from %v, got %v", files, err)
 			}
 
-			fmt.Printf("Configuration:\n  %#v\n", config)
+			configor.logger().Debugf("Configuration:\n  %#v", redact(config))
 		}
 	}()
 
-	configFiles, configModTimeMap := configor.getConfigurationFiles(watchMode, files...)
+	configFiles, configModTimeMap, missing := configor.getConfigurationFiles(watchMode, files...)
+	if len(missing) > 0 && configor.Config.ErrorOnMissingFiles {
+		return &FileNotFoundError{File: missing[0]}, true
+	}
+
+	sourceData, sourcesChanged, err := configor.fetchSources(watchMode)
+	if err != nil {
+		return err, true
+	}
 
-	if watchMode {
+	if watchMode && !sourcesChanged {
 		if len(configModTimeMap) == len(configor.configModTimes) {
 			var changed bool
 			for f, t := range configModTimeMap {
@@ -332,24 +716,70 @@ func (configor *Configor) load(config interface{}, watchMode bool, files ...stri
 		}
 	}
 
+	if configor.Config.TrackProvenance {
+		configor.tracking = newProvenanceTracker(config, configor.Provenance())
+		defer func() {
+			if err == nil {
+				configor.provenance.Store(configor.tracking.report())
+			}
+			configor.tracking = nil
+		}()
+	}
+
 	// process defaults
 	configor.processDefaults(config)
+	configor.tracking.layer(OriginDefault, "", nil)
 
+	dotenv := map[string]string{}
 	for _, file := range configFiles {
 		if configor.Config.Debug || configor.Config.Verbose {
-			fmt.Printf("Loading configurations from file '%v'...\n", file)
+			configor.logger().Debugf("Loading configurations from file '%v'...", file)
 		}
-		if err = processFile(config, file, configor.GetErrorOnUnmatchedKeys()); err != nil {
+		if isDotenvFile(file) {
+			err = processDotenvFile(file, dotenv)
+		} else {
+			err = processFile(config, file, configor.GetErrorOnUnmatchedKeys())
+			configor.tracking.decoded(OriginFile, file, func(blank interface{}) error {
+				return processFile(blank, file, false)
+			})
+		}
+		if err != nil {
 			return err, true
 		}
 	}
 	configor.configModTimes = configModTimeMap
 
-	if prefix := configor.getENVPrefix(config); prefix == "-" {
-		err = configor.processTags(config)
-	} else {
-		err = configor.processTags(config, prefix)
+	for i := len(sourceData) - 1; i >= 0; i-- {
+		source, data := configor.Config.Sources[i], sourceData[i]
+		if configor.Config.Debug || configor.Config.Verbose {
+			configor.logger().Debugf("Loading configurations from source '%v'...", source.Name())
+		}
+		if err = processData(config, data.Data, data.Format, configor.GetErrorOnUnmatchedKeys()); err != nil {
+			return newDecodeError(source.Name(), data.Data, err), true
+		}
+		configor.tracking.decoded(OriginSource, source.Name(), func(blank interface{}) error {
+			return processData(blank, data.Data, data.Format, false)
+		})
+	}
+	configor.sourceData = sourceData
+
+	var prefixes []string
+	if prefix := configor.getENVPrefix(config); prefix != "-" {
+		prefixes = []string{prefix}
+	}
+
+	if err = configor.processDotenv(config, dotenv, prefixes...); err != nil {
+		return err, true
+	}
+
+	if err = configor.processTags(config, "", prefixes...); err != nil {
+		return err, true
+	}
+	configor.tracking.layer(OriginEnv, "", nil)
+
+	if err = configor.processReferences(config); err != nil {
+		return err, true
 	}
 
-	return err, true
+	return validate(config), true
 }
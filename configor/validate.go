@@ -0,0 +1,220 @@
+package configor
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a field that failed validation.
+type FieldError struct {
+	// Path is the path of the field, like `DB.Replicas[1].Port`.
+	Path string
+	// Tag is the validation tag that failed, like `max`.
+	Tag     string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + " " + e.Message
+}
+
+// ValidationError is returned by Load when fields fail validation, it lists
+// every violation found.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// validate checks config against the validation tags of its fields:
+//
+//	min:"1", max:"10"        bounds numbers and durations, or the length of
+//	                         strings, slices and maps
+//	regexp:"^[a-z]+$"        string must match the regular expression
+//	oneof:"debug info warn"  value must be one of the space separated values
+//	format:"url"             string must be an absolute URL
+//	format:"hostport"        string must be a host:port address
+//	format:"file"            string must be the path of an existing file
+//	format:"dir"             string must be the path of an existing directory
+//
+// Blank strings, slices and maps are not validated, use `required:"true"` to
+// reject them. Zero numbers and durations are still checked against min and
+// max.
+func validate(config interface{}) error {
+	var errs []*FieldError
+	walkFields("", reflect.ValueOf(config), "", func(path string, value reflect.Value, tag reflect.StructTag) error {
+		if !value.IsZero() {
+			validateField(path, value, tag, &errs)
+		} else if value.CanInt() || value.CanUint() || value.CanFloat() {
+			validateBounds(path, value, tag, &errs)
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func validateField(path string, value reflect.Value, tag reflect.StructTag, errs *[]*FieldError) {
+	fail := func(name, format string, args ...interface{}) {
+		*errs = append(*errs, &FieldError{Path: path, Tag: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	validateBounds(path, value, tag, errs)
+
+	if pattern := tag.Get("regexp"); pattern != "" {
+		if re, err := regexp.Compile(pattern); err != nil {
+			fail("regexp", "has invalid regexp tag %q: %v", pattern, err)
+		} else if value.Kind() != reflect.String {
+			fail("regexp", "has regexp tag but is not a string")
+		} else if !re.MatchString(value.String()) {
+			fail("regexp", "must match %v, got %q", pattern, value.String())
+		}
+	}
+
+	if oneof := tag.Get("oneof"); oneof != "" {
+		options, actual := strings.Fields(oneof), fmt.Sprint(value.Interface())
+		found := false
+		for _, option := range options {
+			found = found || option == actual
+		}
+		if !found {
+			fail("oneof", "must be one of %v, got %v", strings.Join(options, ", "), actual)
+		}
+	}
+
+	if format := tag.Get("format"); format != "" {
+		if value.Kind() != reflect.String {
+			fail("format", "has format tag but is not a string")
+		} else if err := checkFormat(format, value.String()); err != nil {
+			fail("format", "%v", err)
+		}
+	}
+}
+
+// validateBounds checks value against the min and max tags of its field.
+func validateBounds(path string, value reflect.Value, tag reflect.StructTag, errs *[]*FieldError) {
+	fail := func(name, format string, args ...interface{}) {
+		*errs = append(*errs, &FieldError{Path: path, Tag: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range []string{"min", "max"} {
+		bound := tag.Get(name)
+		if bound == "" {
+			continue
+		}
+
+		cmp, err := compareBound(value, bound)
+		if err != nil {
+			fail(name, "has invalid %v tag %q: %v", name, bound, err)
+		} else if name == "min" && cmp < 0 {
+			fail(name, "must be at least %v, got %v", bound, describeBounded(value))
+		} else if name == "max" && cmp > 0 {
+			fail(name, "must be at most %v, got %v", bound, describeBounded(value))
+		}
+	}
+}
+
+// compareBound compares value, or its length for strings, slices and maps,
+// with the bound of a min or max tag.
+func compareBound(value reflect.Value, bound string) (int, error) {
+	switch {
+	case value.Type() == durationType:
+		limit, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, err
+		}
+		return compare(value.Int(), int64(limit)), nil
+	case value.CanInt():
+		limit, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(value.Int(), limit), nil
+	case value.CanUint():
+		limit, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(value.Uint(), limit), nil
+	case value.CanFloat():
+		limit, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(value.Float(), limit), nil
+	case value.Kind() == reflect.String || value.Kind() == reflect.Slice || value.Kind() == reflect.Map:
+		limit, err := strconv.Atoi(bound)
+		if err != nil {
+			return 0, err
+		}
+		return compare(value.Len(), limit), nil
+	}
+	return 0, fmt.Errorf("cannot bound %v", value.Type())
+}
+
+func compare[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func describeBounded(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return fmt.Sprintf("length %d", value.Len())
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func checkFormat(format, value string) error {
+	switch format {
+	case "url":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute URL, got %q", value)
+		}
+	case "hostport":
+		_, port, err := net.SplitHostPort(value)
+		if err == nil {
+			var n uint64
+			n, err = strconv.ParseUint(port, 10, 16)
+			if err == nil && n == 0 {
+				err = fmt.Errorf("port 0")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("must be a host:port address, got %q", value)
+		}
+	case "file":
+		if info, err := os.Stat(value); err != nil || info.IsDir() {
+			return fmt.Errorf("must be an existing file, got %q", value)
+		}
+	case "dir":
+		if info, err := os.Stat(value); err != nil || !info.IsDir() {
+			return fmt.Errorf("must be an existing directory, got %q", value)
+		}
+	default:
+		return fmt.Errorf("has unknown format %q", format)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package configor
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// fileWatcher reports changes to the entries of watched directories.
+type fileWatcher interface {
+	// Events delivers the paths of changed entries. An empty path means
+	// events were lost and any watched file may have changed.
+	Events() <-chan string
+	Close() error
+}
+
+// watch starts reloading config whenever its configuration files change, by
+// watching them if AutoReloadWatch is set and file watching is supported, or
+// by polling them every AutoReloadInterval otherwise. Files are watched once
+// watch returns, until stopWatch is closed.
+func (configor *Configor) watch(config interface{}, defaultValue reflect.Value, files []string) {
+	var (
+		watcher fileWatcher
+		targets map[string]map[string]bool
+		err     error
+	)
+	if configor.Config.AutoReloadWatch {
+		targets = configor.watchTargets(files)
+		dirs := make([]string, 0, len(targets))
+		for dir := range targets {
+			dirs = append(dirs, dir)
+		}
+		watcher, err = newFileWatcher(dirs)
+	}
+
+	configor.watching.Add(1)
+	go func() {
+		defer configor.watching.Done()
+		if watcher != nil {
+			stopped := configor.watchFiles(watcher, targets, config, defaultValue, files)
+			watcher.Close()
+			if stopped {
+				return
+			}
+			err = errors.New("file watcher stopped")
+		}
+		if err != nil && !configor.Silent {
+			configor.logger().Warnf("Failed to watch configuration %v, polling it instead: %v", files, err)
+		}
+
+		timer := time.NewTimer(configor.Config.AutoReloadInterval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				configor.reload(config, defaultValue, files)
+				timer.Reset(configor.Config.AutoReloadInterval)
+			case <-configor.stopWatch:
+				return
+			}
+		}
+	}()
+}
+
+// watchFiles reloads config once a change to one of the targets has been
+// followed by AutoReloadDebounce without further changes. It returns when
+// the watcher stops delivering events, or true when stopWatch is closed.
+func (configor *Configor) watchFiles(watcher fileWatcher, targets map[string]map[string]bool, config interface{}, defaultValue reflect.Value, files []string) (stopped bool) {
+	var (
+		timer = time.NewTimer(configor.Config.AutoReloadDebounce)
+		fire  <-chan time.Time
+		poll  <-chan time.Time
+	)
+	timer.Stop()
+	defer timer.Stop()
+
+	// sources cannot be watched, keep polling them
+	if len(configor.Config.Sources) > 0 {
+		ticker := time.NewTicker(configor.Config.AutoReloadInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case name, ok := <-watcher.Events():
+			if !ok {
+				return false
+			}
+			if name == "" || isWatchTarget(targets, name) {
+				timer.Reset(configor.Config.AutoReloadDebounce)
+				fire = timer.C
+			}
+		case <-fire:
+			fire = nil
+			// the files did change, even if their modification times did
+			// not move forward, so make load skip that check
+			configor.configModTimes = nil
+			configor.reload(config, defaultValue, files)
+		case <-poll:
+			configor.reload(config, defaultValue, files)
+		case <-configor.stopWatch:
+			return true
+		}
+	}
+}
+
+// watchTargets returns the names, grouped by directory, of every file that
+// getConfigurationFiles may load for files.
+func (configor *Configor) watchTargets(files []string) map[string]map[string]bool {
+	targets := map[string]map[string]bool{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if targets[dir] == nil {
+			targets[dir] = map[string]bool{}
+		}
+		targets[dir][filepath.Base(file)] = true
+		targets[dir][filepath.Base(getConfigurationFileNameWithENVPrefix(file, configor.GetEnvironment()))] = true
+		targets[dir][filepath.Base(getConfigurationFileNameWithENVPrefix(file, "example"))] = true
+	}
+	return targets
+}
+
+// isWatchTarget reports whether a change to path may change the loaded
+// configuration. Kubernetes mounts ConfigMaps as symlinks into a `..data`
+// directory that is swapped atomically, so changes to entries starting with
+// `..` count as well.
+func isWatchTarget(targets map[string]map[string]bool, path string) bool {
+	names := targets[filepath.Dir(path)]
+	if names == nil {
+		return false
+	}
+	name := filepath.Base(path)
+	return names[name] || strings.HasPrefix(name, "..")
+}
+
+// reload loads config again on top of its defaults, or of the current
+// configuration with SafeReload, and swaps it in if anything changed.
+func (configor *Configor) reload(config interface{}, defaultValue reflect.Value, files []string) {
+	base := defaultValue
+	if configor.Config.SafeReload {
+		base = reflect.ValueOf(configor.current.Load()).Elem()
+	}
+	reflectPtr := reflect.New(reflect.ValueOf(config).Elem().Type())
+	reflectPtr.Elem().Set(deepCopy(base))
+
+	if err, changed := configor.load(reflectPtr.Interface(), true, files...); err == nil && changed {
+		configor.reloaded(config, reflectPtr.Interface())
+	} else if err != nil {
+		configor.logger().Errorf("Failed to reload configuration from %v, got error %v", files, err)
+		if configor.Config.AutoReloadErrorCallback != nil {
+			configor.Config.AutoReloadErrorCallback(err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package configor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyWatcher watches directories with inotify. Directories rather than
+// files are watched, so files replaced by rename keep being watched.
+type inotifyWatcher struct {
+	file   *os.File
+	dirs   map[int32]string
+	events chan string
+
+	// done is closed by Close, so an event nobody reads anymore does not
+	// block the reader
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newFileWatcher(dirs []string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	// a non blocking file is read through the runtime poller, so Close
+	// interrupts a pending Read
+	watcher := &inotifyWatcher{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   map[int32]string{},
+		events: make(chan string),
+		done:   make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			watcher.file.Close()
+			return nil, &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+		}
+		watcher.dirs[int32(wd)] = dir
+	}
+	if len(watcher.dirs) == 0 {
+		watcher.file.Close()
+		return nil, errors.New("no directories to watch")
+	}
+
+	go watcher.read()
+	return watcher, nil
+}
+
+func (watcher *inotifyWatcher) Events() <-chan string {
+	return watcher.events
+}
+
+func (watcher *inotifyWatcher) Close() error {
+	watcher.closeOnce.Do(func() {
+		close(watcher.done)
+	})
+	return watcher.file.Close()
+}
+
+func (watcher *inotifyWatcher) read() {
+	defer close(watcher.events)
+
+	var buf [syscall.SizeofInotifyEvent * 4096]byte
+	for {
+		n, err := watcher.file.Read(buf[:])
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+
+			var path string
+			if event.Mask&syscall.IN_Q_OVERFLOW == 0 {
+				dir, ok := watcher.dirs[event.Wd]
+				if !ok || event.Mask&syscall.IN_IGNORED != 0 {
+					continue
+				}
+				name := buf[nameStart:offset]
+				for len(name) > 0 && name[len(name)-1] == 0 {
+					name = name[:len(name)-1]
+				}
+				path = filepath.Join(dir, string(name))
+			}
+			select {
+			case watcher.events <- path:
+			case <-watcher.done:
+				return
+			}
+		}
+	}
+}
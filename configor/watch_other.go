@@ -0,0 +1,9 @@
+//go:build !linux
+
+package configor
+
+import "errors"
+
+func newFileWatcher(dirs []string) (fileWatcher, error) {
+	return nil, errors.New("watching files is not supported on this platform")
+}
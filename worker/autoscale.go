@@ -0,0 +1,95 @@
+package worker
+
+import "time"
+
+// AutoscalePolicy makes a WorkerPool expand itself under queue pressure.
+// The pool never shrinks below the number of workers passed to New; the
+// extra workers quit again by the idle timeout mechanism of Expand.
+type AutoscalePolicy struct {
+	// MaxWorkers caps the number of base plus expanded workers. Defaults to
+	// twice the number of base workers, or the number of CPUs for a pool
+	// without base workers. A cap not above the number of base workers
+	// disables autoscaling.
+	MaxWorkers int
+
+	// Interval is how often the queue is checked. Defaults to 100ms.
+	Interval time.Duration
+
+	// QueueDepth expands the pool when at least this many jobs are waiting
+	// while all workers are busy. Defaults to 1.
+	QueueDepth int
+
+	// WaitTime expands the pool when the average time jobs started during
+	// the last interval spent in the queue is at least this long. Zero
+	// disables this check.
+	WaitTime time.Duration
+
+	// Step is the number of workers added at once. Defaults to the number of
+	// waiting jobs.
+	Step int
+
+	// IdleTimeout is the timeout of the extra workers, see Expand.
+	// Defaults to one second.
+	IdleTimeout time.Duration
+}
+
+func (pool *WorkerPool) autoscale(policy AutoscalePolicy) {
+	defer pool.wg.Done()
+
+	if policy.Interval <= 0 {
+		policy.Interval = 100 * time.Millisecond
+	}
+	if policy.QueueDepth <= 0 {
+		policy.QueueDepth = 1
+	}
+	if policy.IdleTimeout <= 0 {
+		policy.IdleTimeout = time.Second
+	}
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	last := pool.Stats()
+	for {
+		select {
+		case <-ticker.C:
+		case <-pool.quit:
+			return
+		}
+
+		stats := pool.Stats()
+		if n := policy.expansion(stats, last); n > 0 {
+			pool.Expand(n, policy.IdleTimeout, nil)
+		}
+		last = stats
+	}
+}
+
+// expansion returns how many workers should be added given the current and
+// the previous stats.
+func (policy *AutoscalePolicy) expansion(stats, last Stats) int {
+	total := stats.Workers + stats.Expanded
+	if total >= policy.MaxWorkers || stats.Busy < total {
+		return 0
+	}
+
+	pressure := stats.Queued >= policy.QueueDepth
+	if started := stats.Started - last.Started; !pressure && policy.WaitTime > 0 && started > 0 {
+		pressure = (stats.WaitTime-last.WaitTime)/time.Duration(started) >= policy.WaitTime
+	}
+	if !pressure {
+		return 0
+	}
+
+	n := policy.Step
+	if n <= 0 {
+		n = stats.Queued
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > policy.MaxWorkers-total {
+		n = policy.MaxWorkers - total
+	}
+	return n
+}
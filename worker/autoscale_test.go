@@ -0,0 +1,99 @@
+package worker_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoscale(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := worker.NewWithOptions(1, worker.Options{
+		QueueSize: 20,
+		Autoscale: &worker.AutoscalePolicy{
+			MaxWorkers:  4,
+			Interval:    time.Millisecond * 5,
+			IdleTimeout: time.Millisecond * 50,
+		},
+	})
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		assert.True(pool.Queue(func() {
+			defer wg.Done()
+			<-release
+		}))
+	}
+
+	assert.Eventually(func() bool {
+		return pool.Stats().Busy == 4
+	}, _timeout, time.Millisecond)
+
+	<-time.After(time.Millisecond * 50)
+	stats := pool.Stats()
+	assert.Equal(4, stats.Busy)
+	assert.Equal(4, stats.Workers+stats.Expanded)
+
+	close(release)
+	wg.Wait()
+
+	// at most as many extra workers as the pool has base workers may stay
+	// registered until they get another job, see Expand
+	assert.Eventually(func() bool {
+		return pool.Stats().Expanded <= 1
+	}, _timeout, time.Millisecond)
+
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+}
+
+func TestAutoscaleIdle(t *testing.T) {
+	pool := worker.NewWithOptions(2, worker.Options{
+		Autoscale: &worker.AutoscalePolicy{
+			MaxWorkers: 4,
+			Interval:   time.Millisecond,
+		},
+	})
+
+	<-time.After(time.Millisecond * 20)
+	assert.Equal(t, 0, pool.Stats().Expanded)
+
+	assert.NoError(t, waitFunc(pool.Stop, _timeout))
+}
+
+func TestAutoscaleDefaultMaxWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := worker.NewWithOptions(2, worker.Options{
+		QueueSize: 20,
+		Autoscale: &worker.AutoscalePolicy{
+			Interval:    time.Millisecond * 5,
+			IdleTimeout: time.Millisecond * 50,
+		},
+	})
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		assert.True(pool.Queue(func() {
+			defer wg.Done()
+			<-release
+		}))
+	}
+
+	assert.Eventually(func() bool {
+		return pool.Stats().Busy == 4
+	}, _timeout, time.Millisecond)
+	<-time.After(time.Millisecond * 20)
+	assert.Equal(4, pool.Stats().Busy)
+
+	close(release)
+	wg.Wait()
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+}
@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownJobType is returned when enqueuing a job type without handler.
+var ErrUnknownJobType = errors.New("worker: unknown job type")
+
+// Record is a serialised job kept by a Store.
+type Record struct {
+	ID      uint64 `json:"id"`
+	Type    string `json:"type"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Store keeps records until they are acknowledged.
+type Store interface {
+	// Append durably stores a record and returns it with its ID set.
+	Append(rec Record) (Record, error)
+
+	// Ack removes a record once its job succeeded.
+	Ack(id uint64) error
+
+	// Pending returns all records not acknowledged yet, oldest first.
+	Pending() ([]Record, error)
+
+	// Close releases the store.
+	Close() error
+}
+
+// Handler runs the job of a record type.
+type Handler func(ctx context.Context, payload []byte) error
+
+// DurableQueue queues named jobs, stored in a Store, on a WorkerPool. Jobs
+// are delivered at least once: a record is only acknowledged after its
+// handler succeeded, otherwise it is delivered again by Recover.
+type DurableQueue struct {
+	pool  *WorkerPool
+	store Store
+
+	// OnError, if set, is called when a handler fails or a record can not
+	// be acknowledged.
+	OnError func(rec Record, err error)
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	inFlight map[uint64]struct{}
+}
+
+// NewDurableQueue makes a new *DurableQueue running jobs on pool.
+func NewDurableQueue(pool *WorkerPool, store Store) *DurableQueue {
+	return &DurableQueue{
+		pool:     pool,
+		store:    store,
+		handlers: make(map[string]Handler),
+		inFlight: make(map[uint64]struct{}),
+	}
+}
+
+// Register sets the handler of a job type. It should be called for every
+// type before Recover.
+func (q *DurableQueue) Register(name string, h Handler) {
+	q.mu.Lock()
+	q.handlers[name] = h
+	q.mu.Unlock()
+}
+
+// Enqueue stores a job and queues it on the pool. Once stored, the job is
+// not lost even if queuing fails: it is delivered by a later Recover.
+// ctx only bounds queuing, the job itself runs until the pool is stopped.
+func (q *DurableQueue) Enqueue(ctx context.Context, name string, payload []byte) error {
+	q.mu.Lock()
+	_, ok := q.handlers[name]
+	q.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJobType, name)
+	}
+
+	rec, err := q.store.Append(Record{Type: name, Payload: payload})
+	if err != nil {
+		return err
+	}
+	return q.queue(ctx, rec)
+}
+
+// Recover queues every stored record which is neither acknowledged nor
+// already queued, typically after a restart; calling it periodically
+// redelivers failed jobs. Records of unknown types are left in the store.
+// It returns the number of queued records.
+func (q *DurableQueue) Recover(ctx context.Context) (int, error) {
+	recs, err := q.store.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, rec := range recs {
+		q.mu.Lock()
+		_, known := q.handlers[rec.Type]
+		_, busy := q.inFlight[rec.ID]
+		q.mu.Unlock()
+		if !known || busy {
+			continue
+		}
+
+		if err := q.queue(ctx, rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (q *DurableQueue) queue(ctx context.Context, rec Record) error {
+	q.mu.Lock()
+	if _, busy := q.inFlight[rec.ID]; busy {
+		q.mu.Unlock()
+		return nil
+	}
+	q.inFlight[rec.ID] = struct{}{}
+	h := q.handlers[rec.Type]
+	q.mu.Unlock()
+
+	err := q.pool.enqueue(ctx, func() {
+		defer q.done(rec)
+
+		// the job outlives the caller, only the pool may cancel it
+		jobCtx, cancel := q.pool.jobContext(context.WithoutCancel(ctx))
+		defer cancel()
+
+		if err := h(jobCtx, rec.Payload); err != nil {
+			q.error(rec, err)
+			return
+		}
+		if err := q.store.Ack(rec.ID); err != nil {
+			q.error(rec, err)
+		}
+	})
+	if err != nil {
+		q.done(rec)
+	}
+	return err
+}
+
+func (q *DurableQueue) done(rec Record) {
+	q.mu.Lock()
+	delete(q.inFlight, rec.ID)
+	q.mu.Unlock()
+}
+
+func (q *DurableQueue) error(rec Record, err error) {
+	if q.OnError != nil {
+		q.OnError(rec, err)
+	}
+}
@@ -0,0 +1,170 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "jobs.journal")
+
+	s, err := worker.OpenFileStore(path)
+	require.NoError(err)
+
+	a, err := s.Append(worker.Record{Type: "mail", Payload: []byte("a")})
+	require.NoError(err)
+	b, err := s.Append(worker.Record{Type: "mail", Payload: []byte("b")})
+	require.NoError(err)
+	require.NotEqual(a.ID, b.ID)
+	require.NoError(s.Ack(a.ID))
+	require.NoError(s.Close())
+
+	// a crash in the middle of an append
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(err)
+	_, err = f.WriteString(`{"op":"add","id":3,"ty`)
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	s, err = worker.OpenFileStore(path)
+	require.NoError(err)
+	defer s.Close()
+
+	pending, err := s.Pending()
+	require.NoError(err)
+	require.Equal([]worker.Record{b}, pending)
+
+	c, err := s.Append(worker.Record{Type: "mail"})
+	require.NoError(err)
+	require.True(c.ID > b.ID)
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "jobs.journal")
+
+	s, err := worker.OpenFileStore(path)
+	require.NoError(err)
+
+	var last worker.Record
+	for i := 0; i < 1500; i++ {
+		last, err = s.Append(worker.Record{Type: "mail"})
+		require.NoError(err)
+		if i < 1499 {
+			require.NoError(s.Ack(last.ID))
+		}
+	}
+	require.NoError(s.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(err)
+	require.True(info.Size() < 50*1024, "journal was not compacted: %d bytes", info.Size())
+
+	s, err = worker.OpenFileStore(path)
+	require.NoError(err)
+	defer s.Close()
+
+	pending, err := s.Pending()
+	require.NoError(err)
+	require.Equal([]worker.Record{last}, pending)
+}
+
+func TestDurableQueue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "jobs.journal")
+
+	store, err := worker.OpenFileStore(path)
+	require.NoError(err)
+
+	pool := worker.New(2)
+	q := worker.NewDurableQueue(pool, store)
+
+	var (
+		mu   sync.Mutex
+		got  []string
+		fail = true
+	)
+	q.Register("echo", func(_ context.Context, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if string(payload) == "flaky" && fail {
+			fail = false
+			return errors.New("flaky")
+		}
+		got = append(got, string(payload))
+		return nil
+	})
+
+	assert.ErrorIs(q.Enqueue(context.Background(), "nope", nil), worker.ErrUnknownJobType)
+	assert.NoError(q.Enqueue(context.Background(), "echo", []byte("hello")))
+	assert.NoError(q.Enqueue(context.Background(), "echo", []byte("flaky")))
+
+	assert.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1 && !fail
+	}, _timeout, time.Millisecond)
+
+	// the failed job is still stored and redelivered
+	assert.Eventually(func() bool {
+		recs, _ := store.Pending()
+		return len(recs) == 1
+	}, _timeout, time.Millisecond)
+	n, err := q.Recover(context.Background())
+	assert.NoError(err)
+	assert.Equal(1, n)
+
+	assert.Eventually(func() bool {
+		recs, _ := store.Pending()
+		return len(recs) == 0
+	}, _timeout, time.Millisecond)
+	assert.Equal([]string{"hello", "flaky"}, got)
+
+	pool.Stop()
+	require.NoError(store.Close())
+}
+
+func TestDurableQueueRestart(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "jobs.journal")
+
+	// a pool without workers stops with the job still queued
+	store, err := worker.OpenFileStore(path)
+	require.NoError(err)
+	pool := worker.New(0, 10)
+	q := worker.NewDurableQueue(pool, store)
+	q.Register("echo", func(context.Context, []byte) error { return nil })
+	assert.NoError(q.Enqueue(context.Background(), "echo", []byte("survivor")))
+	pool.Stop()
+	require.NoError(store.Close())
+
+	store, err = worker.OpenFileStore(path)
+	require.NoError(err)
+	defer store.Close()
+	pool = worker.New(1)
+	defer pool.Stop()
+	q = worker.NewDurableQueue(pool, store)
+
+	done := make(chan string, 1)
+	q.Register("echo", func(_ context.Context, payload []byte) error {
+		done <- string(payload)
+		return nil
+	})
+
+	n, err := q.Recover(context.Background())
+	assert.NoError(err)
+	assert.Equal(1, n)
+	assert.Equal("survivor", <-done)
+}
@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// FileStore is a Store backed by an append-only journal file. Every change
+// is synced to disk before it is acknowledged to the caller. The journal is
+// compacted once most of its records have been acknowledged.
+type FileStore struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	nextID  uint64
+	pending map[uint64]Record
+	acked   int // acknowledged records still in the journal
+}
+
+// journalEntry is a line of the journal.
+type journalEntry struct {
+	Op string `json:"op"` // "add" or "ack"
+	Record
+}
+
+// compactThreshold is the number of acknowledged records which triggers a
+// compaction, provided they outnumber the pending ones.
+const compactThreshold = 1000
+
+// OpenFileStore opens, or creates, the journal at path and replays it.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		nextID:  1,
+		pending: make(map[uint64]Record),
+	}
+
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileStore) replay() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// a crash while appending may leave a partial last line behind
+	if i := bytes.LastIndexByte(data, '\n'); i+1 < len(data) {
+		data = data[:i+1]
+		if err := os.Truncate(s.path, int64(len(data))); err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("worker: corrupt journal %s at line %d: %v", s.path, line, err)
+		}
+
+		switch e.Op {
+		case "add":
+			s.pending[e.ID] = e.Record
+		case "ack":
+			delete(s.pending, e.ID)
+			s.acked++
+		}
+		if e.ID >= s.nextID {
+			s.nextID = e.ID + 1
+		}
+	}
+	return scanner.Err()
+}
+
+// Append implements Store.
+func (s *FileStore) Append(rec Record) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.ID = s.nextID
+	if err := s.write(journalEntry{Op: "add", Record: rec}); err != nil {
+		return Record{}, err
+	}
+	s.nextID++
+	s.pending[rec.ID] = rec
+	return rec, nil
+}
+
+// Ack implements Store.
+func (s *FileStore) Ack(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[id]; !ok {
+		return nil
+	}
+	if err := s.write(journalEntry{Op: "ack", Record: Record{ID: id}}); err != nil {
+		return err
+	}
+	delete(s.pending, id)
+
+	if s.acked++; s.acked >= compactThreshold && s.acked > len(s.pending) {
+		return s.compact()
+	}
+	return nil
+}
+
+// Pending implements Store.
+func (s *FileStore) Pending() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(), nil
+}
+
+// Close implements Store.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileStore) sorted() []Record {
+	recs := make([]Record, 0, len(s.pending))
+	for _, rec := range s.pending {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].ID < recs[j].ID })
+	return recs
+}
+
+func (s *FileStore) write(e journalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// compact rewrites the journal with the pending records only.
+func (s *FileStore) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	err = writeEntries(w, s.sorted())
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	s.file.Close()
+	if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600); err != nil {
+		return err
+	}
+	s.acked = 0
+	return nil
+}
+
+func writeEntries(w io.Writer, recs []Record) error {
+	enc := json.NewEncoder(w)
+	for _, rec := range recs {
+		if err := enc.Encode(journalEntry{Op: "add", Record: rec}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// Future is the pending result of a job submitted by Submit.
+type Future[T any] struct {
+	done chan struct{}
+
+	mu        sync.Mutex
+	resolved  bool
+	val       T
+	err       error
+	callbacks []func(T, error)
+}
+
+// Submit queues a job returning a value on the pool and returns a Future
+// for its result. Queuing blocks like QueueContext does.
+//
+// The job receives a context that is cancelled when ctx is done or the pool
+// is stopped. If the job could not be queued, or its context is done before
+// a worker picks it up, the Future resolves with the corresponding error.
+// A panicking job resolves the Future with a *PanicError.
+func Submit[T any](pool *WorkerPool, ctx context.Context, job func(context.Context) (T, error)) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+
+	var zero T
+	var started atomic.Bool
+	jobCtx, cancel := pool.jobContext(ctx)
+	context.AfterFunc(jobCtx, func() {
+		if !started.CompareAndSwap(false, true) {
+			return
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = ErrStopped
+		}
+		f.resolve(zero, err)
+	})
+
+	run := func() (bool, *PanicError) {
+		defer cancel()
+		if !started.CompareAndSwap(false, true) {
+			return false, nil
+		}
+		val, panicErr, err := call(jobCtx, job)
+		f.resolve(val, err)
+		return panicErr == nil, panicErr
+	}
+
+	if err := pool.enqueueJob(ctx, run); err != nil {
+		if started.CompareAndSwap(false, true) {
+			f.resolve(zero, err)
+		}
+		cancel()
+	}
+
+	return f
+}
+
+// Done returns a channel which is closed when the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the job to finish and returns its result. If ctx is done
+// first, it returns ctx.Err(); the job itself is not affected.
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (f *Future[T]) resolve(val T, err error) {
+	f.mu.Lock()
+	if f.resolved {
+		f.mu.Unlock()
+		return
+	}
+	f.resolved = true
+	f.val, f.err = val, err
+	callbacks := f.callbacks
+	f.callbacks = nil
+	close(f.done)
+	f.mu.Unlock()
+
+	for _, cb := range callbacks {
+		cb(val, err)
+	}
+}
+
+// then calls cb with the result once it is available, right away if it
+// already is.
+func (f *Future[T]) then(cb func(T, error)) {
+	f.mu.Lock()
+	if !f.resolved {
+		f.callbacks = append(f.callbacks, cb)
+		f.mu.Unlock()
+		return
+	}
+	f.mu.Unlock()
+
+	cb(f.val, f.err)
+}
+
+// call runs job, turning a panic into a *PanicError which is returned both
+// as panicErr and err.
+func call[T any](ctx context.Context, job func(context.Context) (T, error)) (val T, panicErr *PanicError, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+			err = panicErr
+		}
+	}()
+	val, err = job(ctx)
+	return val, nil, err
+}
@@ -0,0 +1,110 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmit(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	f := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		return 42, nil
+	})
+
+	v, err := f.Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal(42, v)
+
+	errBoom := errors.New("boom")
+	g := worker.Submit(pool, context.Background(), func(context.Context) (string, error) {
+		return "", errBoom
+	})
+
+	_, err = g.Wait(context.Background())
+	assert.Equal(errBoom, err)
+}
+
+func TestSubmitPanic(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	f := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		panic("BOOM!")
+	})
+
+	_, err := f.Wait(context.Background())
+	var perr *worker.PanicError
+	if assert.ErrorAs(err, &perr) {
+		assert.Equal("BOOM!", perr.Value)
+		assert.NotEmpty(perr.Stack)
+	}
+
+	// the worker survives the panic
+	g := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+	v, err := g.Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal(1, v)
+}
+
+func TestSubmitStopped(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(0, 10)
+
+	f := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+	g := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), _timeout)
+	defer cancel()
+
+	_, err := f.Wait(ctx)
+	assert.Equal(worker.ErrStopped, err)
+	_, err = g.Wait(ctx)
+	assert.Equal(worker.ErrStopped, err)
+
+	h := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+	_, err = h.Wait(ctx)
+	assert.Equal(worker.ErrStopped, err)
+}
+
+func TestFutureWaitContext(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	f := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	_, err := f.Wait(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+
+	close(release)
+	<-f.Done()
+	v, err := f.Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal(1, v)
+}
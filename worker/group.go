@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs a set of jobs on a WorkerPool and collects the first error,
+// like golang.org/x/sync/errgroup does with goroutines.
+//
+// Waiting for a Group from a job of the same pool can deadlock when all
+// workers end up waiting.
+type Group struct {
+	pool   *WorkerPool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a new Group running jobs on pool, along with a context
+// derived from ctx which is cancelled by the first failing job or by Wait.
+func NewGroup(ctx context.Context, pool *WorkerPool) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{pool: pool, ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go queues job on the pool, blocking while the queue is full. A job which
+// fails, panics or can not be queued cancels the group.
+func (g *Group) Go(job func(context.Context) error) {
+	g.wg.Add(1)
+	Submit(g.pool, g.ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, job(ctx)
+	}).then(func(_ struct{}, err error) {
+		if err != nil {
+			g.fail(err)
+		}
+		g.wg.Done()
+	})
+}
+
+// Wait waits for all queued jobs and returns the first error, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+func (g *Group) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+// ForEach calls fn for every item on pool. It stops at the first error,
+// cancelling the context of the calls still running, and returns it.
+func ForEach[T any](ctx context.Context, pool *WorkerPool, items []T, fn func(context.Context, T) error) error {
+	g, ctx := NewGroup(ctx, pool)
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func(ctx context.Context) error {
+			return fn(ctx, item)
+		})
+	}
+	return g.Wait()
+}
+
+// Map calls fn for every item on pool and returns the results in the order
+// of items. It stops at the first error, cancelling the context of the
+// calls still running, and returns it.
+func Map[T, R any](ctx context.Context, pool *WorkerPool, items []T, fn func(context.Context, T) (R, error)) ([]R, error) {
+	out := make([]R, len(items))
+	g, ctx := NewGroup(ctx, pool)
+	for i, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func(ctx context.Context) error {
+			r, err := fn(ctx, item)
+			if err != nil {
+				return err
+			}
+			out[i] = r
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Pipeline is a pipeline stage: it calls fn on pool for every value read
+// from in, with at most limit calls in flight, and sends the results to
+// the returned channel in the order of in. Stages are chained by feeding
+// the output of one Pipeline into the next.
+//
+// The first error stops the stage: no more values are read from in and
+// the output channel is closed. The returned function waits for the stage
+// to end and returns that error; the output channel must be drained, or
+// ctx cancelled, before calling it.
+func Pipeline[T, R any](ctx context.Context, pool *WorkerPool, in <-chan T, limit int, fn func(context.Context, T) (R, error)) (<-chan R, func() error) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan R)
+	sem := make(chan struct{}, limit)
+	futures := make(chan *Future[R], limit)
+
+	go func() {
+		defer close(futures)
+		for {
+			var item T
+			var ok bool
+			select {
+			case item, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			futures <- Submit(pool, ctx, func(ctx context.Context) (R, error) {
+				return fn(ctx, item)
+			})
+		}
+	}()
+
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(out)
+		defer cancel()
+
+		for f := range futures {
+			r, ferr := f.Wait(context.Background())
+			<-sem
+			if err != nil {
+				continue
+			}
+			if ferr != nil {
+				err = ferr
+				cancel()
+				continue
+			}
+
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+	}()
+
+	return out, func() error {
+		<-done
+		return err
+	}
+}
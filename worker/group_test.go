@@ -0,0 +1,156 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(4, 10)
+	defer pool.Stop()
+
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	out, err := worker.Map(context.Background(), pool, items, func(_ context.Context, i int) (string, error) {
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		return strconv.Itoa(i), nil
+	})
+	assert.NoError(err)
+	for i, s := range out {
+		assert.Equal(strconv.Itoa(i), s)
+	}
+}
+
+func TestMapFirstError(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	errBoom := errors.New("boom")
+	var calls int64
+	out, err := worker.Map(context.Background(), pool, make([]int, 100), func(ctx context.Context, _ int) (int, error) {
+		if atomic.AddInt64(&calls, 1) == 3 {
+			return 0, errBoom
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+		return 1, nil
+	})
+	assert.Equal(errBoom, err)
+	assert.Nil(out)
+	assert.True(atomic.LoadInt64(&calls) < 100)
+}
+
+func TestForEachPanic(t *testing.T) {
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	err := worker.ForEach(context.Background(), pool, []int{1, 2, 3}, func(_ context.Context, i int) error {
+		if i == 2 {
+			panic("BOOM!")
+		}
+		return nil
+	})
+
+	var perr *worker.PanicError
+	assert.ErrorAs(t, err, &perr)
+}
+
+func TestGroupStoppedPool(t *testing.T) {
+	pool := worker.New(1)
+	pool.Stop()
+
+	g, _ := worker.NewGroup(context.Background(), pool)
+	g.Go(func(context.Context) error { return nil })
+	assert.Equal(t, worker.ErrStopped, g.Wait())
+}
+
+func TestPipeline(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(4, 10)
+	defer pool.Stop()
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; i < 50; i++ {
+			in <- i
+		}
+	}()
+
+	var inFlight, maxInFlight int64
+	squares, wait1 := worker.Pipeline(context.Background(), pool, in, 3, func(_ context.Context, i int) (int, error) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(50-i) * time.Microsecond * 20)
+		return i * i, nil
+	})
+	strs, wait2 := worker.Pipeline(context.Background(), pool, squares, 2, func(_ context.Context, i int) (string, error) {
+		return strconv.Itoa(i), nil
+	})
+
+	var got []string
+	for s := range strs {
+		got = append(got, s)
+	}
+	assert.NoError(wait1())
+	assert.NoError(wait2())
+
+	assert.Len(got, 50)
+	for i, s := range got {
+		assert.Equal(strconv.Itoa(i*i), s)
+	}
+	assert.True(atomic.LoadInt64(&maxInFlight) <= 3)
+}
+
+func TestPipelineError(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	errBoom := errors.New("boom")
+	out, wait := worker.Pipeline(context.Background(), pool, in, 2, func(_ context.Context, i int) (int, error) {
+		if i == 5 {
+			return 0, errBoom
+		}
+		return i, nil
+	})
+
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	assert.Equal(errBoom, wait())
+	assert.Equal([]int{0, 1, 2, 3, 4}, got)
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type keyKey struct{}
+
+// WithKey returns a copy of ctx carrying a concurrency key, for example a
+// tenant ID. When Options.KeyConcurrency is set, at most that many jobs
+// with the same key are queued or running at once.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// KeyFrom returns the concurrency key carried by ctx, if any.
+func KeyFrom(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyKey{}).(string)
+	return key, ok
+}
+
+//-----------------------------------------------------------------------------
+
+// keyLimiter limits the number of jobs per key. Jobs over the limit wait
+// before they enter the queue, so they do not hold up other keys.
+type keyLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	keys map[string]*keySlots
+}
+
+type keySlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+func newKeyLimiter(limit int) *keyLimiter {
+	return &keyLimiter{
+		limit: limit,
+		keys:  make(map[string]*keySlots),
+	}
+}
+
+// acquire blocks until a slot for key is free, ctx is done or quit is
+// closed.
+func (l *keyLimiter) acquire(ctx context.Context, quit <-chan struct{}, key string) error {
+	l.mu.Lock()
+	slots, ok := l.keys[key]
+	if !ok {
+		slots = &keySlots{sem: make(chan struct{}, l.limit)}
+		l.keys[key] = slots
+	}
+	slots.refs++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.unref(key, slots)
+		return ctx.Err()
+	case <-quit:
+		l.unref(key, slots)
+		return ErrStopped
+	}
+}
+
+func (l *keyLimiter) release(key string) {
+	l.mu.Lock()
+	slots := l.keys[key]
+	l.mu.Unlock()
+
+	<-slots.sem
+	l.unref(key, slots)
+}
+
+func (l *keyLimiter) unref(key string, slots *keySlots) {
+	l.mu.Lock()
+	if slots.refs--; slots.refs == 0 {
+		delete(l.keys, key)
+	}
+	l.mu.Unlock()
+}
+
+//-----------------------------------------------------------------------------
+
+// rateLimiter is a token bucket, only used by the dispatcher goroutine.
+type rateLimiter struct {
+	interval time.Duration // time to earn one token
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// wait takes a token, waiting for one if needed. It returns false if quit
+// is closed meanwhile.
+func (l *rateLimiter) wait(quit <-chan struct{}) bool {
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(time.Duration(-l.tokens * float64(l.interval)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-quit:
+		return false
+	}
+}
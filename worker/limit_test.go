@@ -0,0 +1,106 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.NewWithOptions(6, worker.Options{QueueSize: 10, KeyConcurrency: 2})
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	var running, max int64
+	var wg sync.WaitGroup
+	ctxA := worker.WithKey(context.Background(), "a")
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			assert.NoError(pool.QueueContext(ctxA, func(context.Context) error {
+				defer wg.Done()
+				n := atomic.AddInt64(&running, 1)
+				defer atomic.AddInt64(&running, -1)
+				for {
+					m := atomic.LoadInt64(&max)
+					if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+						break
+					}
+				}
+				<-release
+				return nil
+			}))
+		}()
+	}
+
+	assert.Eventually(func() bool { return atomic.LoadInt64(&running) == 2 }, _timeout, time.Millisecond)
+
+	// other keys are not held up by "a"
+	done := make(chan struct{})
+	ctxB := worker.WithKey(context.Background(), "b")
+	assert.NoError(pool.QueueContext(ctxB, func(context.Context) error {
+		close(done)
+		return nil
+	}))
+	select {
+	case <-done:
+	case <-time.After(_timeout):
+		t.Fatal("job with another key was blocked")
+	}
+
+	close(release)
+	wg.Wait()
+	assert.Equal(int64(2), atomic.LoadInt64(&max))
+
+	key, ok := worker.KeyFrom(ctxA)
+	assert.True(ok)
+	assert.Equal("a", key)
+}
+
+func TestKeyConcurrencyContext(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.NewWithOptions(2, worker.Options{KeyConcurrency: 1})
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	ctx := worker.WithKey(context.Background(), "a")
+	assert.NoError(pool.QueueContext(ctx, func(context.Context) error {
+		<-release
+		return nil
+	}))
+
+	tctx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, pool.QueueContext(tctx, func(context.Context) error { return nil }))
+
+	close(release)
+	done := make(chan struct{})
+	assert.NoError(pool.QueueContext(ctx, func(context.Context) error {
+		close(done)
+		return nil
+	}))
+	<-done
+}
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.NewWithOptions(4, worker.Options{QueueSize: 10, RateLimit: 100, RateBurst: 2})
+	defer pool.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(7)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		assert.True(pool.Queue(wg.Done))
+	}
+	wg.Wait()
+
+	// two jobs start right away, the other five one every 10ms
+	assert.True(time.Since(start) >= time.Millisecond*45, "took %v", time.Since(start))
+}
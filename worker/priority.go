@@ -0,0 +1,103 @@
+package worker
+
+import "context"
+
+// Priority is the scheduling priority of a job. Jobs of a higher priority
+// are handed to workers before jobs of a lower one.
+type Priority int
+
+// Supported priorities, the zero value is Normal.
+const (
+	Low Priority = iota - 1
+	Normal
+	High
+)
+
+const (
+	numPriorities = 3
+
+	// DefaultStarvationLimit is used when Options.StarvationLimit is zero.
+	DefaultStarvationLimit = 10
+)
+
+// index maps p to its job queue, High being the first one.
+func (p Priority) index() int {
+	switch {
+	case p >= High:
+		return 0
+	case p <= Low:
+		return 2
+	default:
+		return 1
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx carrying p. Jobs queued by
+// QueueContext or Submit with such a context are scheduled with p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority carried by ctx, Normal if none.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return Normal
+}
+
+// scheduler picks the next job for the dispatcher. Lower priorities with
+// waiting jobs age every time a job of a higher priority is picked; once
+// they reach the starvation limit, their next waiting job goes first.
+type scheduler struct {
+	jobs  [numPriorities]chan func()
+	quit  <-chan struct{}
+	limit int
+	aged  [numPriorities]int
+}
+
+// next blocks until a job is available or quit is closed.
+func (s *scheduler) next() (func(), bool) {
+	for i := numPriorities - 1; i > 0; i-- {
+		if s.aged[i] < s.limit {
+			continue
+		}
+		select {
+		case job := <-s.jobs[i]:
+			return s.picked(i, job), true
+		default:
+		}
+	}
+
+	for i := 0; i < numPriorities; i++ {
+		select {
+		case job := <-s.jobs[i]:
+			return s.picked(i, job), true
+		default:
+		}
+	}
+
+	select {
+	case job := <-s.jobs[0]:
+		return s.picked(0, job), true
+	case job := <-s.jobs[1]:
+		return s.picked(1, job), true
+	case job := <-s.jobs[2]:
+		return s.picked(2, job), true
+	case <-s.quit:
+		return nil, false
+	}
+}
+
+func (s *scheduler) picked(i int, job func()) func() {
+	s.aged[i] = 0
+	for j := i + 1; j < numPriorities; j++ {
+		// unbuffered queues can not tell whether someone is waiting
+		if len(s.jobs[j]) > 0 || cap(s.jobs[j]) == 0 {
+			s.aged[j]++
+		}
+	}
+	return job
+}
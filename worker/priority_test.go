@@ -0,0 +1,86 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// queueInOrder blocks the single worker of pool, queues jobs recording
+// their names once run and returns the order they ran in.
+func queueInOrder(t *testing.T, pool *worker.WorkerPool, jobs []string, priorities []worker.Priority) []string {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.True(pool.Queue(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	// held by the dispatcher while waiting for the worker
+	assert.True(pool.Queue(func() {}))
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	for i, name := range jobs {
+		name := name
+		wg.Add(1)
+		ctx := worker.WithPriority(context.Background(), priorities[i])
+		assert.NoError(pool.QueueContext(ctx, func(context.Context) error {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}))
+	}
+
+	close(release)
+	wg.Wait()
+	return order
+}
+
+func TestPriority(t *testing.T) {
+	pool := worker.New(1, 10)
+	defer pool.Stop()
+
+	order := queueInOrder(t, pool,
+		[]string{"low1", "normal1", "high1", "low2", "high2"},
+		[]worker.Priority{worker.Low, worker.Normal, worker.High, worker.Low, worker.High},
+	)
+
+	assert.Equal(t, []string{"high1", "high2", "normal1", "low1", "low2"}, order)
+}
+
+func TestPriorityStarvation(t *testing.T) {
+	pool := worker.NewWithOptions(1, worker.Options{QueueSize: 10, StarvationLimit: 2})
+	defer pool.Stop()
+
+	order := queueInOrder(t, pool,
+		[]string{"low", "h1", "h2", "h3", "h4", "h5"},
+		[]worker.Priority{worker.Low, worker.High, worker.High, worker.High, worker.High, worker.High},
+	)
+
+	// the job held by the dispatcher may already count as a skip
+	var idx int
+	for i, name := range order {
+		if name == "low" {
+			idx = i
+		}
+	}
+	assert.True(t, idx >= 1 && idx <= 2, "low ran at %d: %v", idx, order)
+}
+
+func TestPriorityFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(worker.Normal, worker.PriorityFrom(context.Background()))
+	assert.Equal(worker.High, worker.PriorityFrom(worker.WithPriority(context.Background(), worker.High)))
+}
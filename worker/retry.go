@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	gerrors "github.com/gleez/pkg/errors"
+)
+
+// RetryPolicy describes how a failing job is retried, see Retry.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one.
+	// Defaults to 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the delay after every retry. Defaults to 2.
+	Multiplier float64
+
+	// Jitter randomizes every delay by up to this fraction of it, in both
+	// directions. Zero means no jitter.
+	Jitter float64
+
+	// Retryable reports whether an error is worth retrying. Defaults to
+	// IsRetryable.
+	Retryable func(error) bool
+
+	// DeadLetter, if set, is called with the last error of a job which
+	// failed for good, because it ran out of attempts, its error was not
+	// retryable or its context was done while waiting for a retry.
+	DeadLetter func(err error, attempts int)
+}
+
+// IsRetryable reports whether err carries an Unavailable or
+// DeadlineExceeded error code from the errors package.
+func IsRetryable(err error) bool {
+	var e gerrors.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code() == gerrors.Unavailable || e.Code() == gerrors.DeadlineExceeded
+}
+
+// Retry wraps job so it is retried according to policy. The delays between
+// attempts are spent on the worker running the job and are cut short when
+// the job context is done.
+func Retry(policy RetryPolicy, job func(context.Context) error) func(context.Context) error {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 10 * time.Second
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+
+	return func(ctx context.Context) error {
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			err := job(ctx)
+			if err == nil {
+				return nil
+			}
+			if attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+				policy.deadLetter(err, attempt)
+				return err
+			}
+
+			timer := time.NewTimer(policy.jitter(backoff))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				policy.deadLetter(err, attempt)
+				return err
+			}
+
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+			if backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+// QueueRetry queues job like QueueContext does, retrying it according to
+// policy.
+func (pool *WorkerPool) QueueRetry(ctx context.Context, policy RetryPolicy, job func(context.Context) error) error {
+	return pool.QueueContext(ctx, Retry(policy, job))
+}
+
+func (policy *RetryPolicy) jitter(d time.Duration) time.Duration {
+	if policy.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + policy.Jitter*(2*rand.Float64()-1)))
+}
+
+func (policy *RetryPolicy) deadLetter(err error, attempts int) {
+	if policy.DeadLetter != nil {
+		policy.DeadLetter(err, attempts)
+	}
+}
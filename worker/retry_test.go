@@ -0,0 +1,102 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gerrors "github.com/gleez/pkg/errors"
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(worker.IsRetryable(gerrors.NewError(gerrors.Unavailable, "down")))
+	assert.True(worker.IsRetryable(gerrors.NewError(gerrors.DeadlineExceeded, "slow")))
+	assert.False(worker.IsRetryable(gerrors.NotFoundError("gone")))
+	assert.False(worker.IsRetryable(errors.New("plain")))
+	assert.False(worker.IsRetryable(nil))
+}
+
+func TestRetry(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	var attempts int64
+	f := worker.Submit(pool, context.Background(), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, worker.Retry(worker.RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: time.Millisecond,
+			Jitter:         0.5,
+		}, func(context.Context) error {
+			if atomic.AddInt64(&attempts, 1) < 3 {
+				return gerrors.NewError(gerrors.Unavailable, "try again")
+			}
+			return nil
+		})(ctx)
+	})
+
+	_, err := f.Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal(int64(3), atomic.LoadInt64(&attempts))
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	type dead struct {
+		err      error
+		attempts int
+	}
+	dl := make(chan dead, 2)
+	policy := worker.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		DeadLetter:     func(err error, attempts int) { dl <- dead{err, attempts} },
+	}
+
+	errDown := gerrors.NewError(gerrors.Unavailable, "down")
+	assert.NoError(pool.QueueRetry(context.Background(), policy, func(context.Context) error {
+		return errDown
+	}))
+	d := <-dl
+	assert.Equal(errDown, d.err)
+	assert.Equal(3, d.attempts)
+
+	// not retryable by default
+	errPlain := errors.New("plain")
+	assert.NoError(pool.QueueRetry(context.Background(), policy, func(context.Context) error {
+		return errPlain
+	}))
+	d = <-dl
+	assert.Equal(errPlain, d.err)
+	assert.Equal(1, d.attempts)
+}
+
+func TestRetryStop(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+
+	dl := make(chan int, 1)
+	started := make(chan struct{}, 1)
+	assert.NoError(pool.QueueRetry(context.Background(), worker.RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Hour,
+		Retryable:      func(error) bool { return true },
+		DeadLetter:     func(_ error, attempts int) { dl <- attempts },
+	}, func(context.Context) error {
+		started <- struct{}{}
+		return errors.New("fail")
+	}))
+
+	<-started
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+	assert.Equal(1, <-dl)
+}
@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time of a Scheduler. It can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the part of *time.Timer a Scheduler needs.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+//-----------------------------------------------------------------------------
+
+// Scheduler queues jobs on a WorkerPool at given times. Scheduling stops
+// when either the scheduler or the pool is stopped.
+type Scheduler struct {
+	pool  *WorkerPool
+	clock Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Entry is a job registered with a Scheduler.
+type Entry struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	running  atomic.Bool
+}
+
+// NewScheduler makes a new *Scheduler queuing jobs on pool. A nil clock
+// means SystemClock.
+func NewScheduler(pool *WorkerPool, clock Clock) *Scheduler {
+	if clock == nil {
+		clock = SystemClock
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		pool:   pool,
+		clock:  clock,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// At runs job once at t, or right away if t has passed.
+func (s *Scheduler) At(t time.Time, job func(context.Context) error) *Entry {
+	return s.Schedule(&once{at: t}, job)
+}
+
+// After runs job once after d.
+func (s *Scheduler) After(d time.Duration, job func(context.Context) error) *Entry {
+	return s.At(s.clock.Now().Add(d), job)
+}
+
+// Every runs job every d, starting d from now.
+func (s *Scheduler) Every(d time.Duration, job func(context.Context) error) *Entry {
+	return s.Schedule(Every(d), job)
+}
+
+// Cron runs job according to a cron expression, see ParseCron.
+func (s *Scheduler) Cron(expr string, job func(context.Context) error) (*Entry, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Schedule(schedule, job), nil
+}
+
+// Schedule runs job at every activation time of schedule. An activation is
+// skipped while the previous run of the job is still queued or running, and
+// activations missed while queuing a job are not made up for.
+func (s *Scheduler) Schedule(schedule Schedule, job func(context.Context) error) *Entry {
+	e := &Entry{stop: make(chan struct{})}
+
+	s.wg.Add(1)
+	go s.run(e, schedule, job)
+	return e
+}
+
+// Stop stops scheduling and waits for the scheduling goroutines to return.
+// Contexts of jobs queued by the scheduler are cancelled.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(e *Entry, schedule Schedule, job func(context.Context) error) {
+	defer s.wg.Done()
+
+	next := schedule.Next(s.clock.Now())
+	for !next.IsZero() {
+		timer := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-timer.C():
+		case <-e.stop:
+			timer.Stop()
+			return
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-s.pool.quit:
+			timer.Stop()
+			return
+		}
+
+		if e.running.CompareAndSwap(false, true) {
+			err := s.pool.QueueContext(s.ctx, func(ctx context.Context) error {
+				defer e.running.Store(false)
+				return job(ctx)
+			})
+			if err != nil {
+				e.running.Store(false)
+			}
+		}
+
+		next = schedule.Next(next)
+		if now := s.clock.Now(); !next.IsZero() && next.Before(now) {
+			next = schedule.Next(now)
+		}
+	}
+}
+
+// Stop stops scheduling the entry. A run already queued is not affected.
+func (e *Entry) Stop() {
+	e.stopOnce.Do(func() { close(e.stop) })
+}
+
+// Running reports whether a run of the entry is queued or running.
+func (e *Entry) Running() bool {
+	return e.running.Load()
+}
+
+// once is a Schedule with a single activation, it is used by one goroutine
+// only.
+type once struct {
+	at   time.Time
+	done bool
+}
+
+func (o *once) Next(time.Time) time.Time {
+	if o.done {
+		return time.Time{}
+	}
+	o.done = true
+	return o.at
+}
@@ -0,0 +1,174 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeClock only moves when told to.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) worker.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance waits for n timers to be pending and moves the clock by d.
+func (c *fakeClock) Advance(t *testing.T, n int, d time.Duration) {
+	assert.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.timers) >= n
+	}, _timeout, time.Millisecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.now
+	}
+	c.timers = pending
+}
+
+func TestSchedulerAfterAndAt(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := worker.NewScheduler(pool, clock)
+	defer s.Stop()
+
+	ran := make(chan string, 2)
+	s.After(time.Minute, func(context.Context) error {
+		ran <- "after"
+		return nil
+	})
+	s.At(clock.Now().Add(time.Hour), func(context.Context) error {
+		ran <- "at"
+		return nil
+	})
+
+	clock.Advance(t, 2, time.Second*30)
+	select {
+	case name := <-ran:
+		t.Fatalf("%s ran too early", name)
+	case <-time.After(time.Millisecond * 20):
+	}
+
+	clock.Advance(t, 2, time.Second*30)
+	assert.Equal("after", <-ran)
+
+	clock.Advance(t, 1, time.Hour)
+	assert.Equal("at", <-ran)
+}
+
+func TestSchedulerEverySkipsRunning(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+	defer pool.Stop()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := worker.NewScheduler(pool, clock)
+	defer s.Stop()
+
+	release := make(chan struct{})
+	runs := make(chan struct{}, 10)
+	e := s.Every(time.Minute, func(context.Context) error {
+		runs <- struct{}{}
+		<-release
+		return nil
+	})
+
+	clock.Advance(t, 1, time.Minute)
+	<-runs
+	assert.True(e.Running())
+
+	// still running, these activations are skipped
+	clock.Advance(t, 1, time.Minute)
+	clock.Advance(t, 1, time.Minute)
+	clock.Advance(t, 1, 0) // wait for the last activation to be handled
+	close(release)
+	assert.Eventually(func() bool { return !e.Running() }, _timeout, time.Millisecond)
+	assert.Len(runs, 0)
+
+	clock.Advance(t, 1, time.Minute)
+	<-runs
+
+	e.Stop()
+}
+
+func TestSchedulerCron(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := worker.NewScheduler(pool, clock)
+	defer s.Stop()
+
+	_, err := s.Cron("bogus", func(context.Context) error { return nil })
+	assert.Error(err)
+
+	ran := make(chan time.Time, 1)
+	_, err = s.Cron("*/5 * * * *", func(context.Context) error {
+		ran <- clock.Now()
+		return nil
+	})
+	assert.NoError(err)
+
+	clock.Advance(t, 1, time.Minute*5)
+	assert.Equal(time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC), <-ran)
+}
+
+func TestSchedulerPoolStop(t *testing.T) {
+	pool := worker.New(1)
+	s := worker.NewScheduler(pool, nil)
+	s.Every(time.Hour, func(context.Context) error { return nil })
+
+	pool.Stop()
+	assert.NoError(t, waitFunc(s.Stop, _timeout))
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the state of a WorkerPool.
+type Stats struct {
+	// Workers is the number of base workers, started by New.
+	Workers int
+
+	// Expanded is the number of extra workers, started by Expand, which
+	// have not quit yet.
+	Expanded int
+
+	// Busy is the number of workers running a job.
+	Busy int
+
+	// Queued is the number of jobs accepted but not yet started.
+	Queued int
+
+	// Started is the number of jobs picked up by a worker.
+	Started uint64
+
+	// Completed is the number of jobs which returned normally. Jobs skipped
+	// because their context was done before they started are not counted.
+	Completed uint64
+
+	// Panicked is the number of jobs which panicked.
+	Panicked uint64
+
+	// Rejected is the number of jobs which could not be queued.
+	Rejected uint64
+
+	// WaitTime is the total time started jobs spent in the queue.
+	WaitTime time.Duration
+
+	// RunTime is the total time finished jobs spent running.
+	RunTime time.Duration
+}
+
+// Observer is notified about the life cycle of jobs. Its methods are
+// called synchronously from the goroutine queuing or running the job, so
+// they should return quickly. Embed NopObserver to implement only some of
+// them.
+type Observer interface {
+	// JobQueued is called once a job is accepted by the pool. A worker may
+	// already have picked the job up by then.
+	JobQueued(p Priority)
+
+	// JobStarted is called when a worker picks a job up.
+	JobStarted(wait time.Duration)
+
+	// JobFinished is called when a job returns or panics.
+	JobFinished(wait, run time.Duration)
+
+	// JobPanicked is called when a job panics, after JobFinished.
+	JobPanicked(err *PanicError)
+
+	// JobRejected is called when a job could not be queued.
+	JobRejected(err error)
+}
+
+// NopObserver is an Observer doing nothing.
+type NopObserver struct{}
+
+func (NopObserver) JobQueued(Priority)                       {}
+func (NopObserver) JobStarted(time.Duration)                 {}
+func (NopObserver) JobFinished(time.Duration, time.Duration) {}
+func (NopObserver) JobPanicked(*PanicError)                  {}
+func (NopObserver) JobRejected(error)                        {}
+
+// Stats returns a snapshot of the pool state.
+func (pool *WorkerPool) Stats() Stats {
+	pool.mu.Lock()
+	stats := pool.stats
+	stats.Queued = pool.queued
+	stats.Busy = pool.running
+	pool.mu.Unlock()
+
+	stats.Workers = int(atomic.LoadInt64(&pool.workers))
+	stats.Expanded = int(atomic.LoadInt64(&pool.expanded))
+	return stats
+}
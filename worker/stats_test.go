@@ -0,0 +1,167 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingObserver struct {
+	worker.NopObserver
+	queued, started, finished, panicked, rejected int64
+}
+
+func (o *countingObserver) JobQueued(worker.Priority) { atomic.AddInt64(&o.queued, 1) }
+func (o *countingObserver) JobStarted(time.Duration)  { atomic.AddInt64(&o.started, 1) }
+func (o *countingObserver) JobFinished(time.Duration, time.Duration) {
+	atomic.AddInt64(&o.finished, 1)
+}
+func (o *countingObserver) JobPanicked(*worker.PanicError) { atomic.AddInt64(&o.panicked, 1) }
+func (o *countingObserver) JobRejected(error)              { atomic.AddInt64(&o.rejected, 1) }
+
+func TestStats(t *testing.T) {
+	assert := assert.New(t)
+
+	obs := &countingObserver{}
+	var handled int64
+	pool := worker.NewWithOptions(2, worker.Options{
+		QueueSize: 10,
+		Observer:  obs,
+		PanicHandler: func(*worker.PanicError) {
+			atomic.AddInt64(&handled, 1)
+		},
+	})
+
+	quit := make(chan struct{})
+	pool.Expand(3, 0, quit)
+
+	assert.Eventually(func() bool {
+		stats := pool.Stats()
+		return stats.Workers == 2 && stats.Expanded == 3
+	}, _timeout, time.Millisecond)
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		assert.True(pool.Queue(func() {
+			defer wg.Done()
+			<-release
+		}))
+	}
+	assert.True(pool.Queue(func() {}))
+
+	assert.Eventually(func() bool {
+		stats := pool.Stats()
+		return stats.Busy == 5 && stats.Queued == 1
+	}, _timeout, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.True(pool.Queue(func() { panic("BOOM!") }))
+	assert.Eventually(func() bool {
+		return pool.Stats().Panicked == 1
+	}, _timeout, time.Millisecond)
+
+	_, err := worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		panic("BOOM!")
+	}).Wait(context.Background())
+	var panicErr *worker.PanicError
+	assert.True(errors.As(err, &panicErr))
+	assert.Eventually(func() bool {
+		return pool.Stats().Panicked == 2
+	}, _timeout, time.Millisecond)
+
+	close(quit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(errors.Is(pool.QueueContext(ctx, func(context.Context) error { return nil }), context.Canceled))
+
+	_, err = pool.Shutdown(context.Background())
+	assert.NoError(err)
+
+	stats := pool.Stats()
+	assert.Equal(0, stats.Workers)
+	assert.Equal(0, stats.Expanded)
+	assert.Equal(0, stats.Busy)
+	assert.Equal(0, stats.Queued)
+	assert.Equal(uint64(6), stats.Completed)
+	assert.Equal(uint64(2), stats.Panicked)
+	assert.Equal(uint64(1), stats.Rejected)
+	assert.True(stats.RunTime > 0)
+
+	assert.Equal(int64(8), atomic.LoadInt64(&obs.queued))
+	assert.Equal(int64(8), atomic.LoadInt64(&obs.started))
+	assert.Equal(int64(8), atomic.LoadInt64(&obs.finished))
+	assert.Equal(int64(2), atomic.LoadInt64(&obs.panicked))
+	assert.Equal(int64(2), atomic.LoadInt64(&handled))
+	assert.Equal(int64(1), atomic.LoadInt64(&obs.rejected))
+}
+
+func TestStatsSkipped(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := worker.NewWithOptions(1, worker.Options{QueueSize: 10})
+
+	release := make(chan struct{})
+	assert.True(pool.Queue(func() { <-release }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(pool.QueueContext(ctx, func(context.Context) error { return nil }))
+	f := worker.Submit(pool, ctx, func(context.Context) (int, error) { return 1, nil })
+	cancel()
+	close(release)
+
+	_, err := f.Wait(context.Background())
+	assert.True(errors.Is(err, context.Canceled))
+	_, err = pool.Shutdown(context.Background())
+	assert.NoError(err)
+
+	stats := pool.Stats()
+	assert.Equal(uint64(3), stats.Started)
+	assert.Equal(uint64(1), stats.Completed)
+}
+
+type orderObserver struct {
+	worker.NopObserver
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *orderObserver) record(event string) {
+	o.mu.Lock()
+	o.events = append(o.events, event)
+	o.mu.Unlock()
+}
+
+func (o *orderObserver) JobFinished(time.Duration, time.Duration) { o.record("finished") }
+func (o *orderObserver) JobPanicked(*worker.PanicError)           { o.record("panicked") }
+
+func TestObserverPanicOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	obs := &orderObserver{}
+	pool := worker.NewWithOptions(1, worker.Options{
+		Observer:     obs,
+		PanicHandler: func(*worker.PanicError) {},
+	})
+
+	assert.True(pool.Queue(func() { panic("BOOM!") }))
+	worker.Submit(pool, context.Background(), func(context.Context) (int, error) {
+		panic("BOOM!")
+	}).Wait(context.Background())
+	_, err := pool.Shutdown(context.Background())
+	assert.NoError(err)
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	assert.Equal([]string{"finished", "panicked", "finished", "panicked"}, obs.events)
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// State tells a TypedPool how to manage the state of its workers.
+type State[S any] struct {
+	// New initialises the state of a worker, for example by opening a
+	// database connection.
+	New func() (S, error)
+
+	// Close, if set, releases a state. It is called when the pool is
+	// stopped, and for the state of a job which panicked, since it may have
+	// been left inconsistent.
+	Close func(S)
+}
+
+// TypedPool is a WorkerPool processing inputs of type T into results of type
+// R. Every worker owns a state of type S, initialised once and handed to
+// every job it runs, so expensive resources are not allocated per job. This
+// includes workers added by Expand or autoscaling, their states are closed
+// once they have quit.
+type TypedPool[S, T, R any] struct {
+	*WorkerPool
+
+	state   State[S]
+	handler func(S, T) (R, error)
+
+	// idle states, at most one per alive worker, and whether they have
+	// been closed for good
+	mu     sync.Mutex
+	states []S
+	closed bool
+}
+
+// NewTypedPool makes a new *TypedPool, see NewWithOptions. States are
+// initialised when a worker runs its first job.
+func NewTypedPool[S, T, R any](workers int, opts Options, state State[S], handler func(state S, in T) (R, error)) *TypedPool[S, T, R] {
+	pool := NewWithOptions(workers, opts)
+	return &TypedPool[S, T, R]{
+		WorkerPool: pool,
+		state:      state,
+		handler:    handler,
+	}
+}
+
+// Submit queues in to be processed and returns a Future for its result,
+// see Submit. If the state of the worker can not be initialised, the
+// Future resolves with that error.
+func (p *TypedPool[S, T, R]) Submit(ctx context.Context, in T) *Future[R] {
+	return Submit(p.WorkerPool, ctx, func(context.Context) (R, error) {
+		return p.run(in)
+	})
+}
+
+// Map processes all inputs and returns the results in the same order, see
+// Map.
+func (p *TypedPool[S, T, R]) Map(ctx context.Context, ins []T) ([]R, error) {
+	return Map(ctx, p.WorkerPool, ins, func(_ context.Context, in T) (R, error) {
+		return p.run(in)
+	})
+}
+
+// Stop stops the pool like WorkerPool.Stop and closes all states.
+func (p *TypedPool[S, T, R]) Stop() {
+	p.WorkerPool.Stop()
+	p.closeStates()
+}
+
+// Shutdown drains the pool like WorkerPool.Shutdown and closes all states.
+func (p *TypedPool[S, T, R]) Shutdown(ctx context.Context) (int, error) {
+	abandoned, err := p.WorkerPool.Shutdown(ctx)
+	p.closeStates()
+	return abandoned, err
+}
+
+func (p *TypedPool[S, T, R]) run(in T) (R, error) {
+	s, err := p.acquire()
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+
+	ok := false
+	defer func() {
+		if ok {
+			p.release(s)
+		} else {
+			p.close(s)
+		}
+	}()
+
+	r, err := p.handler(s, in)
+	ok = true
+	return r, err
+}
+
+// acquire takes an idle state or initialises a new one. As many states as
+// jobs running at once are in use, so one per worker.
+func (p *TypedPool[S, T, R]) acquire() (S, error) {
+	p.mu.Lock()
+	if n := len(p.states); n > 0 {
+		s := p.states[n-1]
+		p.states = p.states[:n-1]
+		p.mu.Unlock()
+		return s, nil
+	}
+	p.mu.Unlock()
+	return p.state.New()
+}
+
+// release keeps s for the next job. Idle states beyond the number of alive
+// workers, left by expanded workers which have quit, are closed.
+func (p *TypedPool[S, T, R]) release(s S) {
+	alive := int(atomic.LoadInt64(&p.workers) + atomic.LoadInt64(&p.expanded))
+	p.mu.Lock()
+	if p.closed || p.stopped() {
+		p.mu.Unlock()
+		p.close(s)
+		return
+	}
+	p.states = append(p.states, s)
+	var surplus []S
+	if n := len(p.states); n > alive {
+		surplus = append(surplus, p.states[alive:]...)
+		clear(p.states[alive:])
+		p.states = p.states[:alive]
+	}
+	p.mu.Unlock()
+
+	for _, s := range surplus {
+		p.close(s)
+	}
+}
+
+func (p *TypedPool[S, T, R]) close(s S) {
+	if p.state.Close != nil {
+		p.state.Close(s)
+	}
+}
+
+func (p *TypedPool[S, T, R]) closeStates() {
+	p.mu.Lock()
+	states := p.states
+	p.states = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, s := range states {
+		p.close(s)
+	}
+}
@@ -0,0 +1,169 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleez/pkg/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferStates struct {
+	mu             sync.Mutex
+	created, freed int
+}
+
+func (b *bufferStates) state() worker.State[*bytes.Buffer] {
+	return worker.State[*bytes.Buffer]{
+		New: func() (*bytes.Buffer, error) {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.created++
+			return new(bytes.Buffer), nil
+		},
+		Close: func(*bytes.Buffer) {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.freed++
+		},
+	}
+}
+
+func (b *bufferStates) counts() (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.created, b.freed
+}
+
+func upper(buf *bytes.Buffer, in string) (string, error) {
+	if in == "panic" {
+		panic("BOOM!")
+	}
+	buf.Reset()
+	buf.WriteString(strings.ToUpper(in))
+	return buf.String(), nil
+}
+
+func TestTypedPool(t *testing.T) {
+	assert := assert.New(t)
+
+	states := &bufferStates{}
+	pool := worker.NewTypedPool(3, worker.Options{QueueSize: 10}, states.state(), upper)
+
+	ins := make([]string, 200)
+	for i := range ins {
+		ins[i] = string(rune('a' + i%26))
+	}
+	out, err := pool.Map(context.Background(), ins)
+	assert.NoError(err)
+	for i, s := range out {
+		assert.Equal(strings.ToUpper(ins[i]), s)
+	}
+
+	v, err := pool.Submit(context.Background(), "go").Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal("GO", v)
+
+	created, freed := states.counts()
+	assert.True(created <= 3, "created %d states", created)
+	assert.Equal(0, freed)
+
+	pool.Stop()
+	created, freed = states.counts()
+	assert.Equal(created, freed)
+}
+
+func TestTypedPoolPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	states := &bufferStates{}
+	pool := worker.NewTypedPool(1, worker.Options{}, states.state(), upper)
+
+	_, err := pool.Submit(context.Background(), "panic").Wait(context.Background())
+	var perr *worker.PanicError
+	assert.ErrorAs(err, &perr)
+
+	// the state of the panicking job is dropped
+	created, freed := states.counts()
+	assert.Equal(1, created)
+	assert.Equal(1, freed)
+
+	v, err := pool.Submit(context.Background(), "ok").Wait(context.Background())
+	assert.NoError(err)
+	assert.Equal("OK", v)
+
+	_, err = pool.Shutdown(context.Background())
+	assert.NoError(err)
+	created, freed = states.counts()
+	assert.Equal(2, created)
+	assert.Equal(2, freed)
+}
+
+func TestTypedPoolStateError(t *testing.T) {
+	errInit := errors.New("no connection")
+	pool := worker.NewTypedPool(1, worker.Options{}, worker.State[int]{
+		New: func() (int, error) { return 0, errInit },
+	}, func(int, int) (int, error) { return 1, nil })
+	defer pool.Stop()
+
+	_, err := pool.Submit(context.Background(), 1).Wait(context.Background())
+	assert.Equal(t, errInit, err)
+}
+
+func TestTypedPoolExpanded(t *testing.T) {
+	assert := assert.New(t)
+
+	states := &bufferStates{}
+	pool := worker.NewTypedPool(1, worker.Options{QueueSize: 10}, states.state(), func(buf *bytes.Buffer, in string) (string, error) {
+		time.Sleep(time.Millisecond) // keep all workers busy
+		return upper(buf, in)
+	})
+	quit := make(chan struct{})
+	pool.Expand(3, 0, quit)
+
+	ins := make([]string, 100)
+	for i := range ins {
+		ins[i] = "x"
+	}
+	_, err := pool.Map(context.Background(), ins)
+	assert.NoError(err)
+
+	// expanded workers keep their states too
+	created, freed := states.counts()
+	assert.True(created <= 4, "created %d states", created)
+	assert.Equal(0, freed)
+
+	close(quit)
+	pool.Stop()
+	created, freed = states.counts()
+	assert.Equal(created, freed)
+}
+
+func TestTypedPoolShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	states := &bufferStates{}
+	pool := worker.NewTypedPool(2, worker.Options{QueueSize: 10}, states.state(), func(buf *bytes.Buffer, in string) (string, error) {
+		time.Sleep(20 * time.Millisecond) // still running at the deadline
+		return upper(buf, in)
+	})
+	for i := 0; i < 4; i++ {
+		pool.Submit(context.Background(), "x")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.Shutdown(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+
+	// states released by jobs finishing after the deadline are closed too
+	assert.Eventually(func() bool {
+		created, freed := states.counts()
+		return created > 0 && created == freed
+	}, _timeout, time.Millisecond)
+}
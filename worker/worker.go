@@ -2,12 +2,28 @@
 //
 // Jobs can be queued using the Queue() method which also accepts a timeout parameter for timing out queuing and if all workers are too busy.
 //
+// Context aware jobs can be queued using the QueueContext() method. The job receives a context that gets cancelled when either the caller's context is done or the pool is stopped.
+//
+// A panicking job does not shrink the pool: the panic is reported to Options.PanicHandler and the worker is restarted.
+//
+// Jobs queued with a context carrying a priority set by WithPriority() are dispatched before jobs of lower priorities. Lower priorities are protected from starvation, see Options.StarvationLimit.
+//
+// Shutdown() stops accepting new jobs and drains the queue before stopping the pool, unlike Stop() which drops queued jobs.
+//
 // For expanding the queue, Expand() method can be used, which increases the number of workers. If a timeout is provided, these extra workers will stop, if there are not enough jobs to do. It is also possible to explicitly stop extra workers by providing a quit channel.
+//
+// Instead of calling Expand() by hand, Options.Autoscale can be set to expand the pool automatically when jobs pile up in the queue.
 package worker
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,10 +31,89 @@ import (
 
 //-----------------------------------------------------------------------------
 
+// ErrStopped is returned when a job is queued on a stopped or shutting down
+// pool.
+var ErrStopped = errors.New("worker: pool stopped")
+
+// PanicError is the error reported when a job panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker: job panicked: %v", e.Value)
+}
+
+// Options configures a WorkerPool, see NewWithOptions.
+type Options struct {
+	// QueueSize is the size of the buffered job queue. Every priority has
+	// its own queue of this size.
+	QueueSize int
+
+	// StarvationLimit is the number of higher priority jobs which may be
+	// dispatched before a waiting lower priority job goes first. Defaults to
+	// DefaultStarvationLimit.
+	StarvationLimit int
+
+	// PanicHandler is called when a job panics. The panicking worker is
+	// replaced by a fresh one, so the pool keeps its size. When nil, the
+	// panic and its stack trace are written to the standard logger.
+	PanicHandler func(*PanicError)
+
+	// Observer, if set, is notified about the life cycle of jobs.
+	Observer Observer
+
+	// RateLimit caps the number of jobs started per second. Zero means no
+	// limit.
+	RateLimit float64
+
+	// RateBurst is the number of jobs which may be started at once, before
+	// RateLimit kicks in. Defaults to one.
+	RateBurst int
+
+	// KeyConcurrency caps the number of queued or running jobs sharing the
+	// same key, see WithKey. Queuing a job whose key is at the limit blocks
+	// until a job with that key finishes. Zero means no limit.
+	KeyConcurrency int
+
+	// Autoscale, if set, expands the pool automatically under queue
+	// pressure.
+	Autoscale *AutoscalePolicy
+}
+
 // WorkerPool provides a pool of workers.
 type WorkerPool struct {
 	pool chan chan func()
-	jobs chan func()
+	jobs [numPriorities]chan func()
+
+	starvationLimit int
+	rate            *rateLimiter
+	keys            *keyLimiter
+
+	onPanic  func(*PanicError)
+	observer Observer
+
+	// number of alive base and expanded workers
+	workers  int64
+	expanded int64
+
+	// ctx is cancelled when the pool is stopped, it is the parent of
+	// all contexts handed to jobs queued by QueueContext.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards the job accounting used by Shutdown.
+	mu      sync.Mutex
+	closing bool
+	halted  bool // queued jobs have been dropped
+	queued  int  // accepted but not yet started jobs
+	running int
+	stats   Stats         // cumulative counters
+	idle    chan struct{} // closed once closing and no jobs are left
 
 	quit     chan struct{}
 	quitOnce sync.Once
@@ -27,23 +122,69 @@ type WorkerPool struct {
 
 // New makes a new *WorkerPool.
 func New(workers int, jobQueue ...int) *WorkerPool {
+	var opts Options
+	if len(jobQueue) > 0 {
+		opts.QueueSize = jobQueue[0]
+	}
+	return NewWithOptions(workers, opts)
+}
+
+// NewWithOptions makes a new *WorkerPool configured by opts.
+func NewWithOptions(workers int, opts Options) *WorkerPool {
 	q := 0
-	if len(jobQueue) > 0 && jobQueue[0] > 0 {
-		q = jobQueue[0]
+	if opts.QueueSize > 0 {
+		q = opts.QueueSize
+	}
+	if opts.PanicHandler == nil {
+		opts.PanicHandler = logPanic
+	}
+	if opts.Observer == nil {
+		opts.Observer = NopObserver{}
+	}
+	if opts.StarvationLimit <= 0 {
+		opts.StarvationLimit = DefaultStarvationLimit
 	}
 	if workers < 0 {
 		workers = runtime.NumCPU()
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	pool := WorkerPool{
-		pool: make(chan chan func(), workers),
-		jobs: make(chan func(), q),
-		quit: make(chan struct{}),
-		wg:   sync.WaitGroup{},
+		pool:     make(chan chan func(), workers),
+		onPanic:  opts.PanicHandler,
+		observer: opts.Observer,
+		ctx:      ctx,
+		cancel:   cancel,
+		idle:     make(chan struct{}),
+		quit:     make(chan struct{}),
+		wg:       sync.WaitGroup{},
+
+		starvationLimit: opts.StarvationLimit,
+	}
+	for i := range pool.jobs {
+		pool.jobs[i] = make(chan func(), q)
+	}
+	if opts.RateLimit > 0 {
+		pool.rate = newRateLimiter(opts.RateLimit, opts.RateBurst)
+	}
+	if opts.KeyConcurrency > 0 {
+		pool.keys = newKeyLimiter(opts.KeyConcurrency)
 	}
 
 	for i := 0; i < workers; i++ {
-		initWorker(i+1, pool.pool, 0, nil, pool.quit, &pool.wg)
+		initWorker(i+1, pool.pool, 0, nil, pool.quit, pool.panicked, &pool.workers, &pool.wg)
+	}
+
+	if opts.Autoscale != nil {
+		policy := *opts.Autoscale
+		if policy.MaxWorkers <= 0 {
+			policy.MaxWorkers = 2 * workers
+			if workers == 0 {
+				policy.MaxWorkers = runtime.NumCPU()
+			}
+		}
+		pool.wg.Add(1)
+		go pool.autoscale(policy)
 	}
 
 	go pool.dispatch()
@@ -52,32 +193,253 @@ func New(workers int, jobQueue ...int) *WorkerPool {
 
 // Queue queues a job to be run by a worker.
 func (pool *WorkerPool) Queue(job func(), timeout ...time.Duration) bool {
+	ctx := context.Background()
+	if len(timeout) > 0 && timeout[0] > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout[0])
+		defer cancel()
+	}
+
+	return pool.enqueue(ctx, job) == nil
+}
+
+// QueueContext queues a context aware job to be run by a worker. Queuing
+// blocks until a worker accepts the job, ctx is done or the pool is stopped.
+// The job is scheduled with the priority set by WithPriority, if any, and
+// limited by the key set by WithKey, if any.
+//
+// The job receives a context that is cancelled when ctx is done or the pool
+// is stopped, whichever happens first. If that context is already done by the
+// time a worker picks the job up, the job is skipped. The error returned by
+// the job is discarded; use Submit to get it back.
+func (pool *WorkerPool) QueueContext(ctx context.Context, job func(context.Context) error) error {
+	jobCtx, cancel := pool.jobContext(ctx)
+	run := func() (bool, *PanicError) {
+		defer cancel()
+		if jobCtx.Err() != nil {
+			return false, nil
+		}
+		_ = job(jobCtx)
+		return true, nil
+	}
+
+	if err := pool.enqueueJob(ctx, run); err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// enqueue pushes job to the dispatcher, it gives up when ctx is done or
+// the pool is stopped. A nil job is queued but not called.
+func (pool *WorkerPool) enqueue(ctx context.Context, job func()) error {
+	return pool.enqueueJob(ctx, func() (bool, *PanicError) {
+		if job != nil {
+			job()
+		}
+		return true, nil
+	})
+}
+
+// enqueueJob is like enqueue for jobs which recover their own panics or may
+// be skipped, job returns false when it panicked or was skipped so it is not
+// counted as completed. A recovered panic is returned by job and reported
+// once the job has finished, like the panics recovered by workers.
+func (pool *WorkerPool) enqueueJob(ctx context.Context, job func() (bool, *PanicError)) (err error) {
+	defer func() {
+		if err != nil {
+			pool.rejected(err)
+		}
+	}()
+
 	if pool.stopped() {
-		return false
+		return ErrStopped
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	var t <-chan time.Time
-	if len(timeout) > 0 && timeout[0] > 0 {
-		t = time.After(timeout[0])
+	key, keyed := KeyFrom(ctx)
+	keyed = keyed && pool.keys != nil
+	if keyed {
+		if err := pool.keys.acquire(ctx, pool.quit, key); err != nil {
+			return err
+		}
+	}
+
+	pool.mu.Lock()
+	if pool.closing || pool.halted {
+		pool.mu.Unlock()
+		if keyed {
+			pool.keys.release(key)
+		}
+		return ErrStopped
+	}
+	pool.queued++
+	pool.mu.Unlock()
+
+	queuedAt := time.Now()
+	run := func() {
+		wait := pool.started(queuedAt)
+		var (
+			ok       bool
+			panicErr *PanicError
+		)
+		defer func() {
+			if keyed {
+				pool.keys.release(key)
+			}
+			pool.finished(wait, time.Since(queuedAt)-wait, ok)
+			if panicErr != nil {
+				pool.panicked(panicErr)
+			}
+		}()
+		ok, panicErr = job()
 	}
 
+	p := PriorityFrom(ctx)
 	select {
-	case pool.jobs <- job:
-	case <-t:
-		return false
+	case pool.jobs[p.index()] <- run:
+		pool.observer.JobQueued(p)
+		return nil
+	case <-ctx.Done():
+		pool.abandon(key, keyed)
+		return ctx.Err()
 	case <-pool.quit:
-		return false
+		pool.abandon(key, keyed)
+		return ErrStopped
 	}
+}
 
-	return true
+func (pool *WorkerPool) started(queuedAt time.Time) time.Duration {
+	wait := time.Since(queuedAt)
+	pool.mu.Lock()
+	if !pool.halted {
+		pool.queued--
+	}
+	pool.running++
+	pool.stats.Started++
+	pool.stats.WaitTime += wait
+	pool.mu.Unlock()
+
+	pool.observer.JobStarted(wait)
+	return wait
+}
+
+func (pool *WorkerPool) finished(wait, run time.Duration, ok bool) {
+	pool.mu.Lock()
+	pool.running--
+	pool.stats.RunTime += run
+	if ok {
+		pool.stats.Completed++
+	}
+	pool.checkIdle()
+	pool.mu.Unlock()
+
+	pool.observer.JobFinished(wait, run)
+}
+
+func (pool *WorkerPool) panicked(err *PanicError) {
+	pool.mu.Lock()
+	pool.stats.Panicked++
+	pool.mu.Unlock()
+
+	pool.observer.JobPanicked(err)
+	pool.onPanic(err)
+}
+
+func (pool *WorkerPool) rejected(err error) {
+	pool.mu.Lock()
+	pool.stats.Rejected++
+	pool.mu.Unlock()
+
+	pool.observer.JobRejected(err)
+}
+
+func (pool *WorkerPool) abandon(key string, keyed bool) {
+	if keyed {
+		pool.keys.release(key)
+	}
+
+	pool.mu.Lock()
+	if !pool.halted {
+		pool.queued--
+	}
+	pool.checkIdle()
+	pool.mu.Unlock()
+}
+
+// checkIdle must be called with mu held.
+func (pool *WorkerPool) checkIdle() {
+	if pool.closing && pool.queued == 0 && pool.running == 0 {
+		select {
+		case <-pool.idle:
+		default:
+			close(pool.idle)
+		}
+	}
+}
+
+// jobContext derives a context from ctx which is also cancelled when the
+// pool is stopped.
+func (pool *WorkerPool) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(pool.ctx, cancel)
+	return jobCtx, func() {
+		stop()
+		cancel()
+	}
 }
 
-// Stop stops the pool and waits for all workers to return.
+// Stop stops the pool and waits for all workers to return. Contexts handed
+// to jobs queued by QueueContext are cancelled.
 func (pool *WorkerPool) Stop() {
-	pool.quitOnce.Do(func() { close(pool.quit) })
+	pool.halt()
 	pool.wg.Wait()
 }
 
+// halt stops the pool without waiting for running jobs to return. Jobs which
+// have not been started yet are dropped, their number is returned by the
+// call that stopped the pool.
+func (pool *WorkerPool) halt() (dropped int) {
+	pool.quitOnce.Do(func() {
+		close(pool.quit)
+		pool.cancel()
+
+		// a dropped job may still be handed to a worker by the dispatcher,
+		// halted keeps it from being taken off the queued jobs twice
+		pool.mu.Lock()
+		pool.halted = true
+		dropped, pool.queued = pool.queued, 0
+		pool.checkIdle()
+		pool.mu.Unlock()
+	})
+	return dropped
+}
+
+// Shutdown stops accepting new jobs, waits for every already queued job to
+// finish and then stops the pool. If ctx is done first, the pool is stopped
+// right away: jobs which have not been started yet are dropped and their
+// number is returned along with ctx.Err(). Running jobs are not waited for
+// then, their contexts are cancelled but jobs ignoring them keep running in
+// the background. If the pool is stopped by Stop meanwhile, Shutdown waits for
+// the running jobs only; the jobs dropped by Stop are not counted.
+func (pool *WorkerPool) Shutdown(ctx context.Context) (abandoned int, err error) {
+	pool.mu.Lock()
+	pool.closing = true
+	pool.checkIdle()
+	pool.mu.Unlock()
+
+	select {
+	case <-pool.idle:
+		pool.Stop()
+	case <-ctx.Done():
+		err = ctx.Err()
+		abandoned = pool.halt()
+	}
+	return abandoned, err
+}
+
 // Expand is for putting more 'Worker's into work. If there is'nt any job to do,
 // and a timeout is set, they will simply get timed-out.
 // Default behaviour is they will timeout in a sliding manner.
@@ -104,7 +466,7 @@ func (pool *WorkerPool) Expand(n int, timeout time.Duration, quit <-chan struct{
 		return false
 	}
 	for i := 0; i < n; i++ {
-		initWorker(i+1, pool.pool, timeout, quit, pool.quit, &pool.wg)
+		initWorker(i+1, pool.pool, timeout, quit, pool.quit, pool.panicked, &pool.expanded, &pool.wg)
 	}
 	return true
 }
@@ -114,12 +476,28 @@ func (pool *WorkerPool) stopped() bool {
 }
 
 func (pool *WorkerPool) dispatch() {
+	s := scheduler{
+		jobs:  pool.jobs,
+		quit:  pool.quit,
+		limit: pool.starvationLimit,
+	}
 	for {
+		job, ok := s.next()
+		if !ok {
+			return
+		}
+		if pool.rate != nil && !pool.rate.wait(pool.quit) {
+			return
+		}
+
+		//handle job
 		select {
-		case job := <-pool.jobs:
-			//handle job
-			todo := <-pool.pool
-			todo <- job
+		case todo := <-pool.pool:
+			select {
+			case todo <- job:
+			case <-pool.quit:
+				return
+			}
 		case <-pool.quit:
 			return
 		}
@@ -135,10 +513,13 @@ type worker struct {
 	todo     chan func()
 	timeout  time.Duration
 	quit     <-chan struct{}
+	onPanic  func(*PanicError)
+	alive    *int64
 }
 
 func (w *worker) begin(wg *sync.WaitGroup) {
 	defer wg.Done()
+	defer w.exit(wg)
 	var timeout <-chan time.Time
 
 	for {
@@ -191,12 +572,33 @@ func (w *worker) begin(wg *sync.WaitGroup) {
 	}
 }
 
+// exit runs when the worker goroutine returns. A panicking job is reported
+// and a replacement goroutine is started for this worker, unless the worker
+// was meant to quit anyway.
+func (w *worker) exit(wg *sync.WaitGroup) {
+	if r := recover(); r != nil {
+		if w.onPanic != nil {
+			w.onPanic(&PanicError{Value: r, Stack: debug.Stack()})
+		}
+
+		if !stopped(w.poolQuit) && !stopped(w.quit) {
+			wg.Add(1)
+			go w.begin(wg)
+			return
+		}
+	}
+
+	atomic.AddInt64(w.alive, -1)
+}
+
 func initWorker(
 	id int,
 	pool chan chan func(),
 	timeout time.Duration,
 	quit <-chan struct{},
 	poolQuit <-chan struct{},
+	onPanic func(*PanicError),
+	alive *int64,
 	wg *sync.WaitGroup) *worker {
 	if stopped(poolQuit) {
 		return nil
@@ -208,8 +610,11 @@ func initWorker(
 		timeout:  timeout,
 		quit:     quit,
 		poolQuit: poolQuit,
+		onPanic:  onPanic,
+		alive:    alive,
 	}
 
+	atomic.AddInt64(alive, 1)
 	wg.Add(1)
 	go w.begin(wg)
 
@@ -218,6 +623,10 @@ func initWorker(
 
 //-----------------------------------------------------------------------------
 
+func logPanic(err *PanicError) {
+	log.Printf("%v\n%s", err, err.Stack)
+}
+
 func stopped(c <-chan struct{}) bool {
 	ok := true
 	select {
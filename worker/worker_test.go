@@ -1,6 +1,7 @@
 package worker_test
 
 import (
+	"context"
 	"errors"
 	"runtime"
 	"sync/atomic"
@@ -195,6 +196,254 @@ func TestTimeoutNoGoroutineLeak(t *testing.T) {
 	assert.NoError(waitFunc(pool.Stop, _timeout))
 }
 
+func TestQueueContext(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2)
+
+	done := make(chan struct{})
+	assert.NoError(pool.QueueContext(context.Background(), func(ctx context.Context) error {
+		defer close(done)
+		return ctx.Err()
+	}))
+	<-done
+
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+}
+
+func TestQueueContextCallerCancel(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+	defer pool.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	assert.NoError(pool.QueueContext(ctx, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		result <- ctx.Err()
+		return ctx.Err()
+	}))
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-result:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(_timeout):
+		t.Fatal("job did not observe cancellation")
+	}
+
+	// queuing itself respects ctx
+	assert.Equal(context.Canceled, pool.QueueContext(ctx, func(context.Context) error { return nil }))
+}
+
+func TestQueueContextBlockedQueue(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(0)
+	defer pool.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	// the dispatcher holds the first job while waiting for a worker
+	assert.NoError(pool.QueueContext(ctx, func(context.Context) error { return nil }))
+
+	err := pool.QueueContext(ctx, func(context.Context) error { return nil })
+	assert.Equal(context.DeadlineExceeded, err)
+}
+
+func TestQueueContextStop(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1)
+
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	assert.NoError(pool.QueueContext(context.Background(), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		result <- ctx.Err()
+		return nil
+	}))
+
+	<-started
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+	assert.Equal(context.Canceled, <-result)
+
+	assert.Equal(worker.ErrStopped, pool.QueueContext(context.Background(), func(context.Context) error { return nil }))
+}
+
+func TestPanicRecovery(t *testing.T) {
+	assert := assert.New(t)
+
+	panics := make(chan *worker.PanicError, 10)
+	pool := worker.NewWithOptions(2, worker.Options{
+		PanicHandler: func(err *worker.PanicError) { panics <- err },
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.True(pool.Queue(func() { panic("BOOM!") }, _timeout))
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case err := <-panics:
+			assert.Equal("BOOM!", err.Value)
+			assert.Contains(string(err.Stack), "TestPanicRecovery")
+		case <-time.After(_timeout):
+			t.Fatal("panic was not reported")
+		}
+	}
+
+	// both workers are still there to take blocking jobs
+	quit := make(chan struct{})
+	var busy int64
+	for i := 0; i < 2; i++ {
+		assert.True(pool.Queue(func() {
+			atomic.AddInt64(&busy, 1)
+			<-quit
+		}, _timeout))
+	}
+	assert.Eventually(func() bool { return atomic.LoadInt64(&busy) == 2 }, _timeout, time.Millisecond)
+	close(quit)
+
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+}
+
+func TestShutdown(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(2, 100)
+
+	var done int64
+	for i := 0; i < 50; i++ {
+		assert.True(pool.Queue(func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&done, 1)
+		}))
+	}
+
+	abandoned, err := pool.Shutdown(context.Background())
+	assert.NoError(err)
+	assert.Equal(0, abandoned)
+	assert.Equal(int64(50), atomic.LoadInt64(&done))
+
+	assert.False(pool.Queue(func() {}))
+	assert.Equal(worker.ErrStopped, pool.QueueContext(context.Background(), func(context.Context) error { return nil }))
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1, 10)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.True(pool.Queue(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	var done int64
+	for i := 0; i < 5; i++ {
+		assert.True(pool.Queue(func() { atomic.AddInt64(&done, 1) }))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	time.AfterFunc(time.Millisecond*100, func() { close(release) })
+	abandoned, err := pool.Shutdown(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(5, abandoned)
+	assert.Equal(int64(0), atomic.LoadInt64(&done))
+}
+
+func TestShutdownDeadlineStuckJob(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1, 10)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	assert.True(pool.Queue(func() {
+		close(started)
+		<-release // ignores the pool being stopped
+	}))
+	<-started
+	assert.True(pool.Queue(func() {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	begin := time.Now()
+	abandoned, err := pool.Shutdown(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(1, abandoned)
+	assert.Less(time.Since(begin), time.Millisecond*500)
+}
+
+func TestQueueNil(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1, 10)
+
+	assert.True(pool.Queue(nil))
+	_, err := pool.Shutdown(context.Background())
+	assert.NoError(err)
+	assert.Equal(uint64(0), pool.Stats().Panicked)
+}
+
+func TestShutdownAfterStop(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1, 10)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.True(pool.Queue(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	assert.True(pool.Queue(func() {}))
+	assert.True(pool.Queue(func() {}))
+
+	time.AfterFunc(time.Millisecond*20, func() { close(release) })
+	assert.NoError(waitFunc(pool.Stop, _timeout))
+	assert.Equal(0, pool.Stats().Queued)
+
+	ctx, cancel := context.WithTimeout(context.Background(), _timeout)
+	defer cancel()
+	abandoned, err := pool.Shutdown(ctx)
+	assert.NoError(err)
+	assert.Equal(0, abandoned)
+}
+
+func TestStopDuringShutdown(t *testing.T) {
+	assert := assert.New(t)
+	pool := worker.New(1, 10)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.True(pool.Queue(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	assert.True(pool.Queue(func() {}))
+	assert.True(pool.Queue(func() {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), _timeout)
+	defer cancel()
+	time.AfterFunc(time.Millisecond*20, func() {
+		go pool.Stop()
+		time.AfterFunc(time.Millisecond*20, func() { close(release) })
+	})
+	abandoned, err := pool.Shutdown(ctx)
+	assert.NoError(err)
+	assert.Equal(0, abandoned)
+	assert.Equal(0, pool.Stats().Queued)
+}
+
 func ExampleWorkerPool() {
 	pool := worker.New(-1)
 